@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
 	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/scheduler"
 	"github.com/josinSbazin/idea-bot/internal/storage"
 	"github.com/josinSbazin/idea-bot/internal/telegram"
 	"github.com/josinSbazin/idea-bot/internal/web"
@@ -35,6 +37,19 @@ func main() {
 	if cfg.Web.Username == "" || cfg.Web.Password == "" {
 		log.Fatal("WEB_USERNAME and WEB_PASSWORD are required")
 	}
+	switch cfg.Telegram.Mode {
+	case telegram.ModePolling:
+	case telegram.ModeWebhook:
+		if cfg.Telegram.WebhookSecret == "" {
+			log.Fatal("TELEGRAM_WEBHOOK_SECRET is required in webhook mode")
+		}
+	default:
+		log.Fatalf("Invalid TELEGRAM_MODE %q: expected polling or webhook", cfg.Telegram.Mode)
+	}
+
+	if err := service.LoadWorkflow(); err != nil {
+		log.Fatalf("Failed to load workflow: %v", err)
+	}
 
 	// Initialize SQLite
 	if err := storage.Init(cfg.SQLite.Path); err != nil {
@@ -42,44 +57,101 @@ func main() {
 	}
 	defer storage.Close()
 
+	// Initialize attachment storage
+	if err := storage.InitFiles(storage.FileStoreOptions{
+		Backend:     cfg.Attachments.Storage,
+		Dir:         cfg.Attachments.Dir,
+		S3Endpoint:  cfg.S3.Endpoint,
+		S3Bucket:    cfg.S3.Bucket,
+		S3AccessKey: cfg.S3.AccessKey,
+		S3SecretKey: cfg.S3.SecretKey,
+		S3Region:    cfg.S3.Region,
+		S3UseSSL:    cfg.S3.UseSSL,
+	}); err != nil {
+		log.Fatalf("Failed to initialize attachments storage: %v", err)
+	}
+
 	// Create services
 	ideaService := service.NewIdeaService()
+	themeService := service.NewThemeService()
+	projectService := service.NewProjectService()
+	tagService := service.NewTagService()
+	statsService := service.NewStatsService()
+	ideaService.OnCreated(func(*model.Idea) { themeService.Notify() })
+	digestService, err := service.NewDigestService()
+	if err != nil {
+		log.Fatalf("Failed to create digest service: %v", err)
+	}
 
 	// Create Telegram bot
-	bot, err := telegram.NewBot(ideaService)
+	bot, err := telegram.NewBot(ideaService, digestService)
 	if err != nil {
 		log.Fatalf("Failed to create Telegram bot: %v", err)
 	}
 
+	// The bot tells authors when their ideas are merged
+	ideaService.OnMerged(bot.NotifyMerged)
+
 	// Create web handler
-	webHandler, err := web.NewHandler(ideaService)
+	webHandler, err := web.NewHandler(ideaService, digestService, themeService, projectService, tagService, statsService)
 	if err != nil {
 		log.Fatalf("Failed to create web handler: %v", err)
 	}
+	webHandler.AddMetrics(bot)
+
+	// Context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// In webhook mode Telegram updates are served next to the web UI, outside basic auth
+	var handler http.Handler = webHandler.SetupRoutes()
+	if cfg.Telegram.Mode == telegram.ModeWebhook {
+		mux := http.NewServeMux()
+		mux.Handle(telegram.WebhookPath(), web.Recover(bot.WebhookHandler()))
+		mux.Handle("/", handler)
+		handler = mux
+	}
 
 	// Setup HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Web.Port,
-		Handler:      webHandler.SetupRoutes(),
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// Channel to signal shutdown
 	done := make(chan struct{})
 
 	// Start Telegram bot in goroutine
+	botDone := make(chan struct{})
 	go func() {
+		defer close(botDone)
 		if err := bot.Start(ctx); err != nil && err != context.Canceled {
 			log.Printf("Telegram bot error: %v", err)
 		}
 	}()
 
+	// Start background theme clustering
+	go themeService.Run(ctx)
+
+	// Start weekly Telegram digests for allowed groups
+	go bot.RunDigests(ctx)
+
+	// Start weekly email digest scheduler
+	if digestService.EmailEnabled() {
+		schedule, err := scheduler.Parse(cfg.Digest.EmailSchedule)
+		if err != nil {
+			log.Fatalf("Invalid DIGEST_EMAIL_SCHEDULE: %v", err)
+		}
+		go scheduler.Run(ctx, "email digest", schedule, func(ctx context.Context) {
+			if err := digestService.SendEmail(ctx); err != nil {
+				log.Printf("Email digest error: %v", err)
+			}
+		})
+	}
+
 	// Start HTTP server in goroutine
 	go func() {
 		log.Printf("Web server listening on http://localhost:%s", cfg.Web.Port)
@@ -107,6 +179,18 @@ func main() {
 			log.Printf("HTTP server shutdown error: %v", err)
 		}
 
+		// Wait for the bot to stop, in webhook mode it unregisters the webhook
+		select {
+		case <-botDone:
+		case <-shutdownCtx.Done():
+		}
+
+		// Let in-flight Telegram handlers finish, e.g. ideas waiting for Claude
+		bot.Drain()
+
+		// Finish re-analysis started from the web UI
+		webHandler.Drain(cfg.Telegram.DrainTimeout)
+
 		close(done)
 	}()
 
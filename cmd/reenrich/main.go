@@ -0,0 +1,139 @@
+// Command reenrich re-runs Claude enrichment for existing ideas, e.g. after
+// changing SYSTEM_PROMPT_FILE or CLAUDE_MODEL. Previous analyses are kept as versions.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+func main() {
+	idsFlag := flag.String("ids", "", "comma-separated idea IDs to re-enrich")
+	statusFlag := flag.String("status", "", "re-enrich all ideas with these comma-separated statuses")
+	all := flag.Bool("all", false, "re-enrich every idea")
+	dryRun := flag.Bool("dry-run", false, "only print the ideas that would be re-enriched")
+	delay := flag.Duration("delay", 2*time.Second, "pause between Claude requests")
+	flag.Parse()
+
+	if *idsFlag == "" && *statusFlag == "" && !*all {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.Load()
+	cfg := config.Get()
+	if cfg.Claude.APIKey == "" && !*dryRun {
+		log.Fatal("ANTHROPIC_API_KEY is required")
+	}
+
+	if err := storage.Init(cfg.SQLite.Path); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer storage.Close()
+
+	// Initialize attachment storage
+	if err := storage.InitFiles(storage.FileStoreOptions{
+		Backend:     cfg.Attachments.Storage,
+		Dir:         cfg.Attachments.Dir,
+		S3Endpoint:  cfg.S3.Endpoint,
+		S3Bucket:    cfg.S3.Bucket,
+		S3AccessKey: cfg.S3.AccessKey,
+		S3SecretKey: cfg.S3.SecretKey,
+		S3Region:    cfg.S3.Region,
+		S3UseSSL:    cfg.S3.UseSSL,
+	}); err != nil {
+		log.Fatalf("Failed to initialize attachments storage: %v", err)
+	}
+
+	ideaService := service.NewIdeaService()
+
+	ids, err := selectIDs(ideaService, *idsFlag, *statusFlag)
+	if err != nil {
+		log.Fatalf("Failed to select ideas: %v", err)
+	}
+	log.Printf("Selected %d ideas (model %s)", len(ids), cfg.Claude.Model)
+
+	if *dryRun {
+		for _, id := range ids {
+			log.Printf("Would re-enrich idea #%d", id)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var failed int
+	for i, id := range ids {
+		if ctx.Err() != nil {
+			log.Printf("Interrupted, %d ideas left", len(ids)-i)
+			break
+		}
+
+		enrichCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		idea, err := ideaService.Reenrich(enrichCtx, id)
+		cancel()
+		if err != nil {
+			log.Printf("[%d/%d] idea #%d failed: %v", i+1, len(ids), id, err)
+			failed++
+		} else {
+			log.Printf("[%d/%d] idea #%d -> version %d", i+1, len(ids), id, idea.EnrichmentVersion)
+		}
+
+		if i < len(ids)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(*delay):
+			}
+		}
+	}
+
+	if failed > 0 {
+		log.Fatalf("%d of %d ideas failed", failed, len(ids))
+	}
+}
+
+// selectIDs resolves the idea IDs from explicit IDs or a status filter
+func selectIDs(ideaService *service.IdeaService, idsFlag, statusFlag string) ([]int64, error) {
+	if idsFlag != "" {
+		var ids []int64
+		for _, s := range strings.Split(idsFlag, ",") {
+			id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(s), "#"), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	var filter model.IdeaFilter
+	if statusFlag != "" {
+		for _, s := range strings.Split(statusFlag, ",") {
+			filter.Status = append(filter.Status, model.IdeaStatus(strings.TrimSpace(s)))
+		}
+	}
+
+	ideas, err := ideaService.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(ideas))
+	for i, idea := range ideas {
+		ids[i] = idea.ID
+	}
+	return ids, nil
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -12,8 +14,20 @@ import (
 
 type Config struct {
 	Telegram struct {
-		BotToken      string  `mapstructure:"bot_token"`
-		AllowedGroups []int64 `mapstructure:"-"`
+		BotToken        string           `mapstructure:"bot_token"`
+		Mode            string           `mapstructure:"mode"`
+		WebhookURL      string           `mapstructure:"webhook_url"`
+		WebhookSecret   string           `mapstructure:"webhook_secret"`
+		PrivateAnnounce bool             `mapstructure:"private_announce"`
+		Workers         int              `mapstructure:"workers"`
+		QueueSize       int              `mapstructure:"queue_size"`
+		DrainTimeout    time.Duration    `mapstructure:"drain_timeout"`
+		AllowedGroups   []int64          `mapstructure:"-"`
+		AllowedTopics   []Topic          `mapstructure:"-"`
+		TopicCategories map[Topic]string `mapstructure:"-"`
+		Admins          []int64          `mapstructure:"-"`
+		DigestSchedule  string           `mapstructure:"digest_schedule"`
+		DigestSchedules map[int64]string `mapstructure:"-"`
 	} `mapstructure:"telegram"`
 
 	Claude struct {
@@ -38,9 +52,58 @@ type Config struct {
 		Global  int `mapstructure:"global"`
 	} `mapstructure:"rate_limit"`
 
+	SMTP struct {
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		From     string `mapstructure:"from"`
+	} `mapstructure:"smtp"`
+
+	Digest struct {
+		EmailRecipients []string `mapstructure:"-"`
+		EmailSchedule   string   `mapstructure:"email_schedule"`
+		StaleDays       int      `mapstructure:"stale_days"`
+	} `mapstructure:"digest"`
+
+	Clarify struct {
+		Enabled      bool          `mapstructure:"enabled"`
+		MaxQuestions int           `mapstructure:"max_questions"`
+		Timeout      time.Duration `mapstructure:"timeout"`
+	} `mapstructure:"clarify"`
+
+	Themes struct {
+		Interval time.Duration `mapstructure:"interval"`
+	} `mapstructure:"themes"`
+
+	Attachments struct {
+		Storage   string `mapstructure:"storage"`
+		Dir       string `mapstructure:"dir"`
+		MaxSizeMB int    `mapstructure:"max_size_mb"`
+	} `mapstructure:"attachments"`
+
+	S3 struct {
+		Endpoint  string `mapstructure:"endpoint"`
+		Bucket    string `mapstructure:"bucket"`
+		AccessKey string `mapstructure:"access_key"`
+		SecretKey string `mapstructure:"secret_key"`
+		Region    string `mapstructure:"region"`
+		UseSSL    bool   `mapstructure:"use_ssl"`
+	} `mapstructure:"s3"`
+
+	Workflow struct {
+		File string `mapstructure:"file"`
+	} `mapstructure:"workflow"`
+
 	Env string `mapstructure:"env"`
 }
 
+// Topic identifies a forum topic of a supergroup; ThreadID 0 is the General topic
+type Topic struct {
+	ChatID   int64
+	ThreadID int
+}
+
 var (
 	instance *Config
 	once     sync.Once
@@ -55,15 +118,39 @@ func Load() {
 
 		// Defaults
 		viper.SetDefault("web.port", "8080")
+		viper.SetDefault("telegram.mode", "polling")
+		viper.SetDefault("telegram.private_announce", false)
+		viper.SetDefault("telegram.workers", 8)
+		viper.SetDefault("telegram.queue_size", 32)
+		viper.SetDefault("telegram.drain_timeout", "30s")
 		viper.SetDefault("sqlite.path", "./ideas.db")
 		viper.SetDefault("claude.model", "claude-sonnet-4-20250514")
 		viper.SetDefault("rate_limit.per_user", 5)
 		viper.SetDefault("rate_limit.global", 50)
 		viper.SetDefault("env", "prod")
 		viper.SetDefault("web.base_url", "http://localhost:8080")
+		viper.SetDefault("smtp.port", 587)
+		viper.SetDefault("digest.email_schedule", "mon 09:00")
+		viper.SetDefault("digest.stale_days", 14)
+		viper.SetDefault("themes.interval", "1h")
+		viper.SetDefault("clarify.enabled", false)
+		viper.SetDefault("clarify.max_questions", 3)
+		viper.SetDefault("clarify.timeout", "10m")
+		viper.SetDefault("attachments.storage", "local")
+		viper.SetDefault("attachments.dir", "./attachments")
+		viper.SetDefault("attachments.max_size_mb", 10)
+		viper.SetDefault("s3.use_ssl", true)
 
 		// Bind environment variables
 		viper.BindEnv("telegram.bot_token", "TELEGRAM_BOT_TOKEN")
+		viper.BindEnv("telegram.digest_schedule", "TELEGRAM_DIGEST_SCHEDULE")
+		viper.BindEnv("telegram.mode", "TELEGRAM_MODE")
+		viper.BindEnv("telegram.webhook_url", "TELEGRAM_WEBHOOK_URL")
+		viper.BindEnv("telegram.webhook_secret", "TELEGRAM_WEBHOOK_SECRET")
+		viper.BindEnv("telegram.private_announce", "TELEGRAM_PRIVATE_ANNOUNCE")
+		viper.BindEnv("telegram.workers", "TELEGRAM_WORKERS")
+		viper.BindEnv("telegram.queue_size", "TELEGRAM_QUEUE_SIZE")
+		viper.BindEnv("telegram.drain_timeout", "TELEGRAM_DRAIN_TIMEOUT")
 		viper.BindEnv("claude.api_key", "ANTHROPIC_API_KEY")
 		viper.BindEnv("claude.model", "CLAUDE_MODEL")
 		viper.BindEnv("claude.system_prompt_file", "SYSTEM_PROMPT_FILE")
@@ -74,6 +161,27 @@ func Load() {
 		viper.BindEnv("sqlite.path", "SQLITE_PATH")
 		viper.BindEnv("rate_limit.per_user", "RATE_LIMIT_PER_USER")
 		viper.BindEnv("rate_limit.global", "RATE_LIMIT_GLOBAL")
+		viper.BindEnv("smtp.host", "SMTP_HOST")
+		viper.BindEnv("smtp.port", "SMTP_PORT")
+		viper.BindEnv("smtp.username", "SMTP_USERNAME")
+		viper.BindEnv("smtp.password", "SMTP_PASSWORD")
+		viper.BindEnv("smtp.from", "SMTP_FROM")
+		viper.BindEnv("digest.email_schedule", "DIGEST_EMAIL_SCHEDULE")
+		viper.BindEnv("digest.stale_days", "DIGEST_STALE_DAYS")
+		viper.BindEnv("themes.interval", "THEMES_INTERVAL")
+		viper.BindEnv("clarify.enabled", "CLARIFY_ENABLED")
+		viper.BindEnv("clarify.max_questions", "CLARIFY_MAX_QUESTIONS")
+		viper.BindEnv("clarify.timeout", "CLARIFY_TIMEOUT")
+		viper.BindEnv("attachments.storage", "ATTACHMENTS_STORAGE")
+		viper.BindEnv("attachments.dir", "ATTACHMENTS_DIR")
+		viper.BindEnv("attachments.max_size_mb", "ATTACHMENTS_MAX_SIZE_MB")
+		viper.BindEnv("s3.endpoint", "S3_ENDPOINT")
+		viper.BindEnv("s3.bucket", "S3_BUCKET")
+		viper.BindEnv("s3.access_key", "S3_ACCESS_KEY")
+		viper.BindEnv("s3.secret_key", "S3_SECRET_KEY")
+		viper.BindEnv("s3.region", "S3_REGION")
+		viper.BindEnv("s3.use_ssl", "S3_USE_SSL")
+		viper.BindEnv("workflow.file", "WORKFLOW_FILE")
 		viper.BindEnv("env", "GO_ENV")
 
 		instance = &Config{}
@@ -81,25 +189,114 @@ func Load() {
 			log.Fatalf("Failed to unmarshal config: %v", err)
 		}
 
-		// Parse allowed groups
-		groupsStr := viper.GetString("TELEGRAM_ALLOWED_GROUPS")
-		if groupsStr != "" {
-			for _, g := range strings.Split(groupsStr, ",") {
-				g = strings.TrimSpace(g)
-				if g == "" {
+		// Parse allowed groups and admins
+		instance.Telegram.AllowedGroups = parseIDList(viper.GetString("TELEGRAM_ALLOWED_GROUPS"), "group")
+		instance.Telegram.Admins = parseIDList(viper.GetString("TELEGRAM_ADMINS"), "admin user")
+
+		// Parse forum topics: "<chat_id>:<thread_id>,..." and "<chat_id>:<thread_id>=<category>;..."
+		for _, item := range strings.Split(viper.GetString("TELEGRAM_ALLOWED_TOPICS"), ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			topic, err := parseTopic(item)
+			if err != nil {
+				log.Printf("Warning: invalid allowed topic %q: %v", item, err)
+				continue
+			}
+			instance.Telegram.AllowedTopics = append(instance.Telegram.AllowedTopics, topic)
+		}
+		instance.Telegram.TopicCategories = make(map[Topic]string)
+		for _, entry := range strings.Split(viper.GetString("TELEGRAM_TOPIC_CATEGORIES"), ";") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			key, category, ok := strings.Cut(entry, "=")
+			if !ok {
+				log.Printf("Warning: invalid topic category %q, expected <chat_id>:<thread_id>=<category>", entry)
+				continue
+			}
+			topic, err := parseTopic(key)
+			if err != nil {
+				log.Printf("Warning: invalid topic in topic category %q: %v", entry, err)
+				continue
+			}
+			instance.Telegram.TopicCategories[topic] = strings.TrimSpace(category)
+		}
+
+		// Parse per-group digest schedules: "<chat_id>=<schedule>;<chat_id>=<schedule>"
+		instance.Telegram.DigestSchedules = make(map[int64]string)
+		schedulesStr := viper.GetString("TELEGRAM_DIGEST_SCHEDULES")
+		if schedulesStr != "" {
+			for _, entry := range strings.Split(schedulesStr, ";") {
+				entry = strings.TrimSpace(entry)
+				if entry == "" {
+					continue
+				}
+				parts := strings.SplitN(entry, "=", 2)
+				if len(parts) != 2 {
+					log.Printf("Warning: invalid digest schedule %q, expected <chat_id>=<schedule>", entry)
 					continue
 				}
-				id, err := strconv.ParseInt(g, 10, 64)
+				id, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
 				if err != nil {
-					log.Printf("Warning: invalid group ID %q: %v", g, err)
+					log.Printf("Warning: invalid group ID in digest schedule %q: %v", entry, err)
+					continue
+				}
+				instance.Telegram.DigestSchedules[id] = strings.TrimSpace(parts[1])
+			}
+		}
+
+		// Parse digest email recipients
+		recipientsStr := viper.GetString("DIGEST_EMAIL_RECIPIENTS")
+		if recipientsStr != "" {
+			for _, r := range strings.Split(recipientsStr, ",") {
+				r = strings.TrimSpace(r)
+				if r == "" {
 					continue
 				}
-				instance.Telegram.AllowedGroups = append(instance.Telegram.AllowedGroups, id)
+				instance.Digest.EmailRecipients = append(instance.Digest.EmailRecipients, r)
 			}
 		}
 	})
 }
 
+// parseIDList parses a comma-separated list of Telegram IDs, skipping invalid entries
+func parseIDList(value, kind string) []int64 {
+	var ids []int64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			log.Printf("Warning: invalid %s ID %q: %v", kind, item, err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parseTopic parses a forum topic written as "<chat_id>:<thread_id>"
+func parseTopic(value string) (Topic, error) {
+	chat, thread, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return Topic{}, fmt.Errorf("expected <chat_id>:<thread_id>")
+	}
+	chatID, err := strconv.ParseInt(strings.TrimSpace(chat), 10, 64)
+	if err != nil {
+		return Topic{}, err
+	}
+	threadID, err := strconv.Atoi(strings.TrimSpace(thread))
+	if err != nil {
+		return Topic{}, err
+	}
+	return Topic{ChatID: chatID, ThreadID: threadID}, nil
+}
+
 func Get() *Config {
 	return instance
 }
@@ -0,0 +1,8 @@
+package model
+
+// Answer is a grounded answer to a question about the idea backlog
+type Answer struct {
+	Question string
+	Text     string
+	Sources  []*Idea
+}
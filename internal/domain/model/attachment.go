@@ -0,0 +1,55 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type AttachmentKind string
+
+const (
+	AttachmentPhoto    AttachmentKind = "photo"
+	AttachmentDocument AttachmentKind = "document"
+)
+
+// Attachment is a file sent together with an idea
+type Attachment struct {
+	ID             int64          `json:"id"`
+	IdeaID         int64          `json:"idea_id"`
+	Kind           AttachmentKind `json:"kind"`
+	FileName       string         `json:"file_name"`
+	ContentType    string         `json:"content_type"`
+	Size           int64          `json:"size"`
+	StorageKey     string         `json:"-"`
+	TelegramFileID string         `json:"-"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// IsImage reports whether the attachment can be shown inline and analyzed as an image
+func (a *Attachment) IsImage() bool {
+	return IsVisionImage(a.ContentType)
+}
+
+// AttachmentInput is a downloaded file to be stored with a new idea
+type AttachmentInput struct {
+	Kind           AttachmentKind
+	FileName       string
+	ContentType    string
+	TelegramFileID string
+	Data           []byte
+}
+
+// Image is an image passed to Claude together with the idea text
+type Image struct {
+	MediaType string
+	Data      []byte
+}
+
+// IsVisionImage reports whether Claude accepts the content type as an image
+func IsVisionImage(contentType string) bool {
+	switch strings.ToLower(contentType) {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
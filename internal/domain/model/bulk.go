@@ -0,0 +1,18 @@
+package model
+
+// BulkResult is the outcome of an action applied to several ideas at once
+type BulkResult struct {
+	Succeeded []int64
+	Failed    []BulkFailure
+}
+
+// BulkFailure is an idea a bulk action was not applied to, and why
+type BulkFailure struct {
+	IdeaID int64
+	Reason string
+}
+
+// Fail records an idea the action was not applied to
+func (r *BulkResult) Fail(id int64, reason string) {
+	r.Failed = append(r.Failed, BulkFailure{IdeaID: id, Reason: reason})
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Component is an entry of a project's component catalog. Enrichment is asked to
+// name affected components from the catalog, and the names it returns are
+// mapped back to catalog entries by name or alias.
+type Component struct {
+	ID          int64     `json:"id"`
+	ProjectID   int64     `json:"project_id"`
+	Name        string    `json:"name"`
+	Aliases     []string  `json:"aliases,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Owner       string    `json:"owner,omitempty"` // Telegram username without @
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// componentKey folds a component name for matching: case, spaces and
+// punctuation are ignored, so "Web-UI" and "web ui" are the same component
+func componentKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Keys returns the folded name and aliases of the component
+func (c *Component) Keys() []string {
+	keys := []string{componentKey(c.Name)}
+	for _, a := range c.Aliases {
+		keys = append(keys, componentKey(a))
+	}
+	return keys
+}
+
+// NormalizeComponents maps component names to the names of catalog entries.
+// Names that match no entry are kept as they are and returned as unknown.
+// Duplicates are dropped.
+func NormalizeComponents(catalog []Component, names []string) (normalized, unknown []string) {
+	byKey := make(map[string]string)
+	for _, c := range catalog {
+		for _, key := range c.Keys() {
+			if key != "" {
+				byKey[key] = c.Name
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := componentKey(name)
+		if key == "" {
+			continue
+		}
+		canonical, known := byKey[key]
+		if known {
+			name, key = canonical, componentKey(canonical)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, name)
+		if !known {
+			unknown = append(unknown, name)
+		}
+	}
+	return normalized, unknown
+}
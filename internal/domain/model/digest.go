@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// StatusChange represents a single status transition of an idea
+type StatusChange struct {
+	IdeaID     int64      `json:"idea_id"`
+	IdeaTitle  string     `json:"idea_title"`
+	FromStatus IdeaStatus `json:"from_status"`
+	ToStatus   IdeaStatus `json:"to_status"`
+	Comment    string     `json:"comment,omitempty"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+// StatusCount is the number of ideas in a status
+type StatusCount struct {
+	Status IdeaStatus
+	Count  int
+}
+
+// VotedIdea is an idea with the number of votes it got in a period
+type VotedIdea struct {
+	IdeaID    int64
+	IdeaTitle string
+	Status    IdeaStatus
+	Votes     int
+}
+
+// CategoryIdeas groups ideas under a single category
+type CategoryIdeas struct {
+	Category IdeaCategory
+	Ideas    []*Idea
+}
+
+// Digest is a periodic summary of the idea backlog
+type Digest struct {
+	From          time.Time
+	To            time.Time
+	BaseURL       string
+	NewCount      int
+	NewIdeas      []CategoryIdeas
+	TopVoted      []VotedIdea
+	StatusChanges []StatusChange
+	StaleIdeas    []*Idea
+	StaleDays     int
+	StatusCounts  []StatusCount
+}
+
+// ChangesTo returns status changes that moved ideas into the given status
+func (d *Digest) ChangesTo(status IdeaStatus) []StatusChange {
+	var result []StatusChange
+	for _, c := range d.StatusChanges {
+		if c.ToStatus == status {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// IsEmpty reports whether the digest has nothing to show
+func (d *Digest) IsEmpty() bool {
+	return d.NewCount == 0 && len(d.TopVoted) == 0 && len(d.StatusChanges) == 0 && len(d.StaleIdeas) == 0
+}
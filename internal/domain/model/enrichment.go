@@ -0,0 +1,147 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ManualEditModel is stored as the model name of versions created by editing in the web UI
+const ManualEditModel = "manual"
+
+// EnrichmentVersion is a saved result of one enrichment run of an idea
+type EnrichmentVersion struct {
+	ID           int64         `json:"id"`
+	IdeaID       int64         `json:"idea_id"`
+	Version      int           `json:"version"`
+	EnrichedJSON string        `json:"-"`
+	Enriched     *EnrichedIdea `json:"enriched,omitempty"`
+	Model        string        `json:"model,omitempty"`
+	PromptHash   string        `json:"prompt_hash,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// ParseEnriched parses the EnrichedJSON field into Enriched struct
+func (v *EnrichmentVersion) ParseEnriched() error {
+	if v.EnrichedJSON == "" {
+		return nil
+	}
+	v.Enriched = &EnrichedIdea{}
+	return json.Unmarshal([]byte(v.EnrichedJSON), v.Enriched)
+}
+
+// FieldDiff is a difference in one enriched field between two versions
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// EnrichedField describes a field of EnrichedIdea by its JSON key
+type EnrichedField struct {
+	Key   string
+	Label string
+	Get   func(*EnrichedIdea) string
+}
+
+// EnrichedFields returns the fields of EnrichedIdea in display order.
+// List fields are returned as one item per line.
+func EnrichedFields() []EnrichedField {
+	lines := func(items []string) string {
+		return strings.Join(items, "\n")
+	}
+	return []EnrichedField{
+		{"title", "Название", func(e *EnrichedIdea) string { return e.Title }},
+		{"summary", "Краткое описание", func(e *EnrichedIdea) string { return e.Summary }},
+		{"detailed_description", "Подробное описание", func(e *EnrichedIdea) string { return e.DetailedDesc }},
+		{"category", "Категория", func(e *EnrichedIdea) string { return e.Category }},
+		{"priority", "Приоритет", func(e *EnrichedIdea) string { return e.Priority }},
+		{"complexity", "Сложность", func(e *EnrichedIdea) string { return e.Complexity }},
+		{"affected_components", "Компоненты", func(e *EnrichedIdea) string { return lines(e.AffectedComponents) }},
+		{"user_story", "User Story", func(e *EnrichedIdea) string { return e.UserStory }},
+		{"acceptance_criteria", "Критерии приёмки", func(e *EnrichedIdea) string { return lines(e.AcceptanceCriteria) }},
+		{"technical_notes", "Технические заметки", func(e *EnrichedIdea) string { return e.TechnicalNotes }},
+		{"related_features", "Связанные функции", func(e *EnrichedIdea) string { return lines(e.RelatedFeatures) }},
+		{"potential_risks", "Риски", func(e *EnrichedIdea) string { return lines(e.PotentialRisks) }},
+		{"tags", "Предложенные теги", func(e *EnrichedIdea) string { return lines(e.Tags) }},
+		{"scoring", "Оценка RICE", func(e *EnrichedIdea) string {
+			if e.Scoring == nil {
+				return ""
+			}
+			return e.Scoring.String()
+		}},
+	}
+}
+
+// DiffEnriched returns the fields that differ between two enrichment results
+func DiffEnriched(old, new *EnrichedIdea) []FieldDiff {
+	if old == nil {
+		old = &EnrichedIdea{}
+	}
+	if new == nil {
+		new = &EnrichedIdea{}
+	}
+
+	var diffs []FieldDiff
+	for _, f := range EnrichedFields() {
+		o, n := f.Get(old), f.Get(new)
+		if o != n {
+			diffs = append(diffs, FieldDiff{Field: f.Label, Old: o, New: n})
+		}
+	}
+	return diffs
+}
+
+// ChangedFields returns the JSON keys of enriched fields that differ between two results
+func ChangedFields(old, new *EnrichedIdea) []string {
+	if old == nil {
+		old = &EnrichedIdea{}
+	}
+	if new == nil {
+		new = &EnrichedIdea{}
+	}
+
+	var keys []string
+	for _, f := range EnrichedFields() {
+		if f.Get(old) != f.Get(new) {
+			keys = append(keys, f.Key)
+		}
+	}
+	return keys
+}
+
+// KeepOverrides copies human-overridden fields from the current analysis into a fresh one,
+// so re-enrichment does not undo manual corrections
+func KeepOverrides(fresh, current *EnrichedIdea, fields []string) {
+	if current == nil {
+		return
+	}
+	for _, field := range fields {
+		switch field {
+		case "title":
+			fresh.Title = current.Title
+		case "summary":
+			fresh.Summary = current.Summary
+		case "detailed_description":
+			fresh.DetailedDesc = current.DetailedDesc
+		case "category":
+			fresh.Category = current.Category
+		case "priority":
+			fresh.Priority = current.Priority
+		case "complexity":
+			fresh.Complexity = current.Complexity
+		case "affected_components":
+			fresh.AffectedComponents = current.AffectedComponents
+		case "user_story":
+			fresh.UserStory = current.UserStory
+		case "acceptance_criteria":
+			fresh.AcceptanceCriteria = current.AcceptanceCriteria
+		case "technical_notes":
+			fresh.TechnicalNotes = current.TechnicalNotes
+		case "related_features":
+			fresh.RelatedFeatures = current.RelatedFeatures
+		case "potential_risks":
+			fresh.PotentialRisks = current.PotentialRisks
+		}
+	}
+}
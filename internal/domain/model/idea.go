@@ -2,11 +2,15 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type IdeaStatus string
 
+// Statuses of the default workflow; WORKFLOW_FILE can define others
 const (
 	StatusNew         IdeaStatus = "new"
 	StatusReviewed    IdeaStatus = "reviewed"
@@ -16,21 +20,19 @@ const (
 	StatusImplemented IdeaStatus = "implemented"
 )
 
+// Label returns the label of the status in the active workflow
 func (s IdeaStatus) Label() string {
-	labels := map[IdeaStatus]string{
-		StatusNew:         "Новая",
-		StatusReviewed:    "Рассмотрена",
-		StatusAccepted:    "Принята",
-		StatusRejected:    "Отклонена",
-		StatusInProgress:  "В работе",
-		StatusImplemented: "Реализована",
-	}
-	if l, ok := labels[s]; ok {
-		return l
+	if state := CurrentWorkflow().State(s); state != nil && state.Label != "" {
+		return state.Label
 	}
 	return string(s)
 }
 
+// IsValid reports whether the value is a status of the active workflow
+func (s IdeaStatus) IsValid() bool {
+	return CurrentWorkflow().State(s) != nil
+}
+
 type IdeaCategory string
 
 const (
@@ -55,6 +57,16 @@ func (c IdeaCategory) Label() string {
 	return string(c)
 }
 
+// IsValid reports whether the value is one of the known categories
+func (c IdeaCategory) IsValid() bool {
+	for _, v := range AllCategories() {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
 type IdeaPriority string
 
 const (
@@ -77,6 +89,16 @@ func (p IdeaPriority) Label() string {
 	return string(p)
 }
 
+// IsValid reports whether the value is one of the known priorities
+func (p IdeaPriority) IsValid() bool {
+	for _, v := range AllPriorities() {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
+
 type IdeaComplexity string
 
 const (
@@ -101,6 +123,40 @@ func (c IdeaComplexity) Label() string {
 	return string(c)
 }
 
+// IsValid reports whether the value is one of the known complexities
+func (c IdeaComplexity) IsValid() bool {
+	for _, v := range AllComplexities() {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
+// IdeaSource is how an idea was submitted
+type IdeaSource string
+
+const (
+	SourceCommand IdeaSource = "command"
+	SourceReply   IdeaSource = "reply"
+	SourceForward IdeaSource = "forward"
+	// Ideas created from the AI breakdown of an epic
+	SourceBreakdown IdeaSource = "breakdown"
+)
+
+func (s IdeaSource) Label() string {
+	labels := map[IdeaSource]string{
+		SourceCommand:   "Команда /idea",
+		SourceReply:     "Ответ /idea на сообщение",
+		SourceForward:   "Пересланное сообщение",
+		SourceBreakdown: "Разбивка эпика",
+	}
+	if l, ok := labels[s]; ok {
+		return l
+	}
+	return string(s)
+}
+
 // EnrichedIdea represents the structured response from Claude
 type EnrichedIdea struct {
 	Title              string   `json:"title"`
@@ -115,28 +171,80 @@ type EnrichedIdea struct {
 	TechnicalNotes     string   `json:"technical_notes,omitempty"`
 	RelatedFeatures    []string `json:"related_features,omitempty"`
 	PotentialRisks     []string `json:"potential_risks,omitempty"`
+
+	// UnknownComponents are affected components missing from the project's catalog
+	UnknownComponents []string `json:"unknown_components,omitempty"`
+
+	// Scoring holds the RICE factors proposed by the analysis, effort aside
+	Scoring *Scoring `json:"scoring,omitempty"`
+
+	// Tags are existing tags the analysis suggests for the idea
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Clarification is a question asked to the author before enrichment and their answer
+type Clarification struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
 }
 
 // Idea represents a feature idea
 type Idea struct {
-	ID                 int64          `json:"id"`
-	TelegramMessageID  int64          `json:"telegram_message_id"`
-	TelegramChatID     int64          `json:"telegram_chat_id"`
-	TelegramUserID     int64          `json:"telegram_user_id"`
-	TelegramUsername   string         `json:"telegram_username,omitempty"`
-	TelegramFirstName  string         `json:"telegram_first_name,omitempty"`
-	RawText            string         `json:"raw_text"`
-	EnrichedJSON       string         `json:"enriched_json,omitempty"`
-	Enriched           *EnrichedIdea  `json:"-"`
-	Title              string         `json:"title,omitempty"`
-	Category           IdeaCategory   `json:"category,omitempty"`
-	Priority           IdeaPriority   `json:"priority,omitempty"`
-	Complexity         IdeaComplexity `json:"complexity,omitempty"`
-	AffectedComponents []string       `json:"affected_components,omitempty"`
-	Status             IdeaStatus     `json:"status"`
-	AdminNotes         string         `json:"admin_notes,omitempty"`
-	CreatedAt          time.Time      `json:"created_at"`
-	UpdatedAt          time.Time      `json:"updated_at"`
+	ID                 int64           `json:"id"`
+	TelegramMessageID  int64           `json:"telegram_message_id"`
+	TelegramChatID     int64           `json:"telegram_chat_id"`
+	MessageChatID      int64           `json:"message_chat_id,omitempty"` // chat of TelegramMessageID when it isn't TelegramChatID
+	TelegramUserID     int64           `json:"telegram_user_id"`
+	TelegramUsername   string          `json:"telegram_username,omitempty"`
+	TelegramFirstName  string          `json:"telegram_first_name,omitempty"`
+	RawText            string          `json:"raw_text"`
+	EnrichedJSON       string          `json:"enriched_json,omitempty"`
+	Enriched           *EnrichedIdea   `json:"-"`
+	Title              string          `json:"title,omitempty"`
+	Category           IdeaCategory    `json:"category,omitempty"`
+	Priority           IdeaPriority    `json:"priority,omitempty"`
+	Complexity         IdeaComplexity  `json:"complexity,omitempty"`
+	AffectedComponents []string        `json:"affected_components,omitempty"`
+	Status             IdeaStatus      `json:"status"`
+	AdminNotes         string          `json:"admin_notes,omitempty"`
+	Clarifications     []Clarification `json:"clarifications,omitempty"`
+	EnrichmentVersion  int             `json:"enrichment_version"`
+	OverriddenFields   []string        `json:"overridden_fields,omitempty"`
+	Source             IdeaSource      `json:"source"`
+	OriginalAuthor     OriginalAuthor  `json:"original_author"`
+	SourceChatID       int64           `json:"source_chat_id,omitempty"`
+	SourceMessageID    int64           `json:"source_message_id,omitempty"`
+	SourceURL          string          `json:"source_url,omitempty"`
+	MessageThreadID    int64           `json:"message_thread_id,omitempty"`
+	ProjectID          int64           `json:"project_id,omitempty"`
+	Assignee           string          `json:"assignee,omitempty"` // Telegram username without @
+	Scoring            Scoring         `json:"scoring"`
+	Score              float64         `json:"score"`
+	Tags               []Tag           `json:"tags,omitempty"`
+	Votes              int             `json:"votes"`
+	MergedIntoID       int64           `json:"merged_into_id,omitempty"` // the idea this duplicate was merged into
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+// OriginalAuthor is the person who wrote a message captured as an idea by someone else
+type OriginalAuthor struct {
+	TelegramUserID   int64  `json:"telegram_user_id,omitempty"`
+	TelegramUsername string `json:"telegram_username,omitempty"`
+	Name             string `json:"name,omitempty"`
+}
+
+// IsSet reports whether the original author is known
+func (a OriginalAuthor) IsSet() bool {
+	return a.TelegramUserID != 0 || a.TelegramUsername != "" || a.Name != ""
+}
+
+// String returns @username when available, otherwise the display name
+func (a OriginalAuthor) String() string {
+	if a.TelegramUsername != "" {
+		return "@" + a.TelegramUsername
+	}
+	return a.Name
 }
 
 // ParseEnriched parses the EnrichedJSON field into Enriched struct
@@ -148,6 +256,64 @@ func (i *Idea) ParseEnriched() error {
 	return json.Unmarshal([]byte(i.EnrichedJSON), i.Enriched)
 }
 
+// IsOverridden reports whether an enriched field (by JSON key) was edited by a human
+func (i *Idea) IsOverridden(field string) bool {
+	for _, f := range i.OverriddenFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// TopicURL returns a t.me link to the forum topic the idea was posted in,
+// or an empty string when it was not posted in a topic
+func (i *Idea) TopicURL() string {
+	// Supergroup IDs are -100 followed by the internal ID used in t.me/c links
+	id := strconv.FormatInt(i.TelegramChatID, 10)
+	if i.MessageThreadID == 0 || !strings.HasPrefix(id, "-100") {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), i.MessageThreadID)
+}
+
+// HasTag reports whether the idea is tagged with name
+func (i *Idea) HasTag(name string) bool {
+	for _, t := range i.Tags {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestedTags returns the tags suggested by the analysis that the idea doesn't have yet
+func (i *Idea) SuggestedTags() []string {
+	if i.Enriched == nil {
+		return nil
+	}
+	var tags []string
+	for _, name := range i.Enriched.Tags {
+		if !i.HasTag(name) {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// IsUnknownComponent reports whether an affected component is missing from the project's catalog
+func (i *Idea) IsUnknownComponent(name string) bool {
+	if i.Enriched == nil {
+		return false
+	}
+	for _, c := range i.Enriched.UnknownComponents {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 // AffectedComponentsStr returns affected components as comma-separated string
 func (i *Idea) AffectedComponentsStr() string {
 	if len(i.AffectedComponents) == 0 {
@@ -171,15 +337,104 @@ type CreateIdeaInput struct {
 	TelegramUsername  string
 	TelegramFirstName string
 	RawText           string
+	Clarifications    []Clarification
+
+	// Chat the message was sent in when it isn't TelegramChatID, e.g. the private
+	// chat of an idea submitted to a group from there
+	MessageChatID int64
+
+	// Set when the idea was captured from someone else's message (reply or forward)
+	Source          IdeaSource
+	OriginalAuthor  OriginalAuthor
+	SourceChatID    int64
+	SourceMessageID int64
+	SourceURL       string
+
+	// Forum topic the idea was posted in, and the category preset for that topic.
+	// A preset category is kept over the one chosen by the AI.
+	MessageThreadID int64
+	Category        IdeaCategory
+
+	// Project of the chat, resolved by IdeaService
+	ProjectID int64
+
+	// Files sent with the idea, already downloaded
+	Attachments []AttachmentInput
 }
 
 // IdeaFilter represents filters for listing ideas
 type IdeaFilter struct {
-	Status   []IdeaStatus
-	Category []IdeaCategory
-	Priority []IdeaPriority
-	Limit    int
-	Offset   int
+	Status         []IdeaStatus
+	Category       []IdeaCategory
+	Priority       []IdeaPriority
+	TelegramChatID int64
+	ProjectID      int64
+	Assignee       string   // Telegram username, case-insensitive
+	Tags           []string // ideas must have every tag
+	MinScore       float64
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	UpdatedBefore  time.Time
+	Sort           IdeaSort // SortCreated when empty
+	Limit          int
+	Cursor         string // position returned in IdeaPage, used by ListPage only
+}
+
+// IdeaSort is the order of an idea list
+type IdeaSort string
+
+const (
+	SortCreated    IdeaSort = "created"    // newest first, the default
+	SortUpdated    IdeaSort = "updated"    // recently updated first
+	SortPriority   IdeaSort = "priority"   // most urgent first
+	SortVotes      IdeaSort = "votes"      // most voted first
+	SortComplexity IdeaSort = "complexity" // simplest first
+	SortScore      IdeaSort = "score"      // highest RICE score first
+)
+
+func (s IdeaSort) Label() string {
+	labels := map[IdeaSort]string{
+		SortCreated:    "Сначала новые",
+		SortUpdated:    "Недавно обновлённые",
+		SortPriority:   "По приоритету",
+		SortVotes:      "По голосам",
+		SortComplexity: "Сначала простые",
+		SortScore:      "По оценке RICE",
+	}
+	if l, ok := labels[s]; ok {
+		return l
+	}
+	return string(s)
+}
+
+// IsValid reports whether the value is one of the known sort orders
+func (s IdeaSort) IsValid() bool {
+	for _, v := range AllSorts() {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AllSorts returns all possible sort orders of idea lists
+func AllSorts() []IdeaSort {
+	return []IdeaSort{
+		SortCreated,
+		SortUpdated,
+		SortPriority,
+		SortVotes,
+		SortComplexity,
+		SortScore,
+	}
+}
+
+// IdeaPage is one page of a keyset-paginated idea list
+type IdeaPage struct {
+	Ideas []*Idea
+	// Cursors of the neighbouring pages, empty when there is none
+	NextCursor string
+	PrevCursor string
 }
 
 // IdeaSummary is a lightweight representation of idea for duplicate checking
@@ -189,16 +444,9 @@ type IdeaSummary struct {
 	RawText string
 }
 
-// AllStatuses returns all possible statuses
+// AllStatuses returns the statuses of the active workflow
 func AllStatuses() []IdeaStatus {
-	return []IdeaStatus{
-		StatusNew,
-		StatusReviewed,
-		StatusAccepted,
-		StatusRejected,
-		StatusInProgress,
-		StatusImplemented,
-	}
+	return CurrentWorkflow().Statuses()
 }
 
 // AllCategories returns all possible categories
@@ -221,3 +469,14 @@ func AllPriorities() []IdeaPriority {
 		PriorityCritical,
 	}
 }
+
+// AllComplexities returns all possible complexities
+func AllComplexities() []IdeaComplexity {
+	return []IdeaComplexity{
+		ComplexityTrivial,
+		ComplexitySmall,
+		ComplexityMedium,
+		ComplexityLarge,
+		ComplexityEpic,
+	}
+}
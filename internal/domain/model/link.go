@@ -0,0 +1,96 @@
+package model
+
+// LinkType is the kind of a link between two ideas as it is stored
+type LinkType string
+
+const (
+	LinkRelated LinkType = "related"
+	LinkBlocks  LinkType = "blocks" // the first idea blocks the second one
+	LinkParent  LinkType = "parent" // the first idea is an epic, the second one is its part
+)
+
+// IdeaRelation is a link seen from one of the linked ideas: "this idea <relation> the other one"
+type IdeaRelation string
+
+const (
+	RelationRelated   IdeaRelation = "related"
+	RelationBlocks    IdeaRelation = "blocks"
+	RelationBlockedBy IdeaRelation = "blocked_by"
+	RelationParentOf  IdeaRelation = "parent_of"
+	RelationChildOf   IdeaRelation = "child_of"
+)
+
+func (r IdeaRelation) Label() string {
+	labels := map[IdeaRelation]string{
+		RelationRelated:   "Связана с",
+		RelationBlocks:    "Блокирует",
+		RelationBlockedBy: "Зависит от",
+		RelationParentOf:  "Эпик для",
+		RelationChildOf:   "Часть эпика",
+	}
+	if l, ok := labels[r]; ok {
+		return l
+	}
+	return string(r)
+}
+
+// Link returns the stored link type of the relation and whether this idea comes first in it
+func (r IdeaRelation) Link() (LinkType, bool) {
+	switch r {
+	case RelationBlocks:
+		return LinkBlocks, true
+	case RelationBlockedBy:
+		return LinkBlocks, false
+	case RelationParentOf:
+		return LinkParent, true
+	case RelationChildOf:
+		return LinkParent, false
+	default:
+		return LinkRelated, true
+	}
+}
+
+func (r IdeaRelation) IsValid() bool {
+	switch r {
+	case RelationRelated, RelationBlocks, RelationBlockedBy, RelationParentOf, RelationChildOf:
+		return true
+	}
+	return false
+}
+
+// RelationOf returns the relation of a stored link seen from the idea that comes first
+// in it when outgoing is true, or from the second one otherwise
+func RelationOf(t LinkType, outgoing bool) IdeaRelation {
+	switch {
+	case t == LinkBlocks && outgoing:
+		return RelationBlocks
+	case t == LinkBlocks:
+		return RelationBlockedBy
+	case t == LinkParent && outgoing:
+		return RelationParentOf
+	case t == LinkParent:
+		return RelationChildOf
+	default:
+		return RelationRelated
+	}
+}
+
+// AllRelations returns all relations in display order
+func AllRelations() []IdeaRelation {
+	return []IdeaRelation{RelationRelated, RelationBlocks, RelationBlockedBy, RelationParentOf, RelationChildOf}
+}
+
+// LinkedIdea is an idea linked to the one being viewed
+type LinkedIdea struct {
+	LinkID   int64        `json:"link_id"`
+	Relation IdeaRelation `json:"relation"`
+	IdeaID   int64        `json:"idea_id"`
+	Title    string       `json:"title"`
+	Status   IdeaStatus   `json:"status"`
+}
+
+// BreakdownItem is a part of an epic proposed by the AI, to be created as a child idea
+type BreakdownItem struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// IdeaMerge records a duplicate merged into the idea that was kept, with the text
+// the duplicate was submitted with and the comments left on its status changes
+type IdeaMerge struct {
+	IdeaID   int64     `json:"idea_id"`
+	MergedID int64     `json:"merged_id"`
+	RawText  string    `json:"raw_text"`
+	Author   string    `json:"author"`
+	Comments string    `json:"comments,omitempty"` // one "«status»: comment" per line
+	MergedAt time.Time `json:"merged_at"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Project groups ideas from one or more Telegram chats. Each project can have its
+// own system prompt, component catalog, rate limits and web login.
+type Project struct {
+	ID           int64         `json:"id"`
+	Name         string        `json:"name"`
+	SystemPrompt string        `json:"system_prompt,omitempty"`
+	Components   []Component   `json:"components,omitempty"`
+	Chats        []ProjectChat `json:"chats"`
+
+	// Zero limits fall back to RATE_LIMIT_PER_USER and RATE_LIMIT_GLOBAL
+	RateLimitPerUser int `json:"rate_limit_per_user"`
+	RateLimitGlobal  int `json:"rate_limit_global"`
+
+	// Project members log in to the web UI with these credentials and only see the project's ideas
+	WebUsername     string `json:"web_username,omitempty"`
+	WebPasswordHash string `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProjectChat links a Telegram chat, or a single forum topic of it, to a project.
+// ThreadID 0 covers the whole chat.
+type ProjectChat struct {
+	ChatID   int64 `json:"chat_id"`
+	ThreadID int64 `json:"thread_id,omitempty"`
+}
+
+// OwnerOf returns the owner of the first affected component that has one
+// in the project's catalog, or an empty string
+func (p *Project) OwnerOf(components []string) string {
+	owners := make(map[string]string)
+	for _, c := range p.Components {
+		if c.Owner != "" {
+			owners[c.Name] = c.Owner
+		}
+	}
+	for _, name := range components {
+		if owner, ok := owners[name]; ok {
+			return owner
+		}
+	}
+	return ""
+}
+
+func (c ProjectChat) String() string {
+	if c.ThreadID == 0 {
+		return fmt.Sprint(c.ChatID)
+	}
+	return fmt.Sprintf("%d:%d", c.ChatID, c.ThreadID)
+}
@@ -0,0 +1,72 @@
+package model
+
+import "fmt"
+
+// Scoring holds the RICE factors of an idea
+type Scoring struct {
+	Reach      int     `json:"reach"`            // users affected per quarter
+	Impact     float64 `json:"impact"`           // one of ImpactLevels
+	Confidence int     `json:"confidence"`       // percent
+	Effort     float64 `json:"effort,omitempty"` // person-days
+}
+
+// IsSet reports whether the idea has been scored
+func (s Scoring) IsSet() bool {
+	return s.Effort > 0
+}
+
+// Score returns the RICE score: reach × impact × confidence / effort, 0 when effort is unknown
+func (s Scoring) Score() float64 {
+	if s.Effort <= 0 {
+		return 0
+	}
+	return float64(s.Reach) * s.Impact * float64(s.Confidence) / 100 / s.Effort
+}
+
+// String returns the factors in a short form for version diffs
+func (s Scoring) String() string {
+	str := fmt.Sprintf("R %d · I %g · C %d%%", s.Reach, s.Impact, s.Confidence)
+	if s.Effort > 0 {
+		str += fmt.Sprintf(" · E %g", s.Effort)
+	}
+	return str
+}
+
+// ImpactLevel is one of the values allowed for the impact factor
+type ImpactLevel struct {
+	Value float64
+	Label string
+}
+
+// ImpactLevels returns the allowed impact values, highest first
+func ImpactLevels() []ImpactLevel {
+	return []ImpactLevel{
+		{3, "Огромное"},
+		{2, "Высокое"},
+		{1, "Среднее"},
+		{0.5, "Низкое"},
+		{0.25, "Минимальное"},
+	}
+}
+
+// IsImpactLevel reports whether v is one of ImpactLevels
+func IsImpactLevel(v float64) bool {
+	for _, l := range ImpactLevels() {
+		if l.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Effort returns the default effort in person-days for a complexity, 0 when it is unknown
+func (c IdeaComplexity) Effort() float64 {
+	efforts := map[IdeaComplexity]float64{
+		ComplexityTrivial: 0.1,
+		ComplexitySmall:   0.5,
+		ComplexityMedium:  2,
+		ComplexityLarge:   7,
+		ComplexityEpic:    20,
+	}
+	return efforts[c]
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Dashboard holds the aggregates shown on the analytics page
+type Dashboard struct {
+	Total        int
+	Weeks        []WeekCount
+	ByStatus     []StatBucket
+	ByCategory   []StatBucket
+	ByPriority   []StatBucket
+	ByComplexity []StatBucket
+
+	// Medians over ideas that reached a decision or got implemented
+	TimeToDecision       DurationStat
+	TimeToImplementation DurationStat
+
+	TopSubmitters []SubmitterCount
+	Groups        []GroupStat
+}
+
+// WeekCount is the number of ideas submitted in the week starting on Monday Week
+type WeekCount struct {
+	Week  time.Time
+	Count int
+}
+
+// StatBucket is the number of ideas with one value of a field
+type StatBucket struct {
+	Label string
+	Count int
+}
+
+// DurationStat is the median of durations measured over Count ideas
+type DurationStat struct {
+	Median time.Duration
+	Count  int
+}
+
+// String formats the median in days, or hours when it is shorter than a day
+func (d DurationStat) String() string {
+	if d.Count == 0 {
+		return "—"
+	}
+	if d.Median < 24*time.Hour {
+		return fmt.Sprintf("%.0f ч", d.Median.Hours())
+	}
+	return strings.Replace(fmt.Sprintf("%.1f дн.", d.Median.Hours()/24), ".", ",", 1)
+}
+
+// SubmitterCount is the number of ideas submitted by a Telegram user
+type SubmitterCount struct {
+	Name  string
+	Count int
+}
+
+// GroupStat is the state of the ideas submitted in a Telegram group
+type GroupStat struct {
+	ChatID      int64
+	Project     string
+	Total       int
+	Open        int
+	Implemented int
+}
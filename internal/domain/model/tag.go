@@ -0,0 +1,50 @@
+package model
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Tag is a colored label attached to ideas, independent of their category
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"` // #rrggbb
+	IdeaCount int       `json:"idea_count,omitempty"`
+	CreatedAt time.Time `json:"-"`
+}
+
+var tagColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// IsTagColor reports whether s is a color in the #rrggbb form
+func IsTagColor(s string) bool {
+	return tagColorRe.MatchString(s)
+}
+
+// tagPalette holds the colors given to new tags
+var tagPalette = []string{
+	"#6366f1", "#0ea5e9", "#14b8a6", "#22c55e", "#eab308",
+	"#f97316", "#ef4444", "#ec4899", "#a855f7", "#64748b",
+}
+
+// DefaultTagColor picks a palette color for a new tag, the same one for the same name
+func DefaultTagColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return tagPalette[h.Sum32()%uint32(len(tagPalette))]
+}
+
+// TextColor returns a text color readable on the tag's background
+func (t Tag) TextColor() string {
+	if !IsTagColor(t.Color) {
+		return "#ffffff"
+	}
+	rgb, _ := strconv.ParseUint(t.Color[1:], 16, 32)
+	r, g, b := float64(rgb>>16&0xff), float64(rgb>>8&0xff), float64(rgb&0xff)
+	if 0.299*r+0.587*g+0.114*b > 160 {
+		return "#1f2937"
+	}
+	return "#ffffff"
+}
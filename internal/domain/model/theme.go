@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Theme is a named cluster of related ideas
+type Theme struct {
+	ID          int64         `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	IdeaCount   int           `json:"idea_count"`
+	Ideas       []IdeaSummary `json:"ideas,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// WorkflowState is a status of the idea workflow with the statuses it can move to
+type WorkflowState struct {
+	Status      IdeaStatus   `json:"status"`
+	Label       string       `json:"label"`
+	Transitions []IdeaStatus `json:"transitions,omitempty"`
+	// CommentRequired means moving an idea into this status needs a comment, e.g. a rejection reason
+	CommentRequired bool `json:"comment_required,omitempty"`
+	// Terminal states are final, ideas can't leave them
+	Terminal bool `json:"terminal,omitempty"`
+}
+
+// Workflow defines the statuses of ideas and the allowed transitions between them
+type Workflow struct {
+	Initial IdeaStatus      `json:"initial"`
+	States  []WorkflowState `json:"states"`
+}
+
+// DefaultWorkflow is used when WORKFLOW_FILE is not set
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Initial: StatusNew,
+		States: []WorkflowState{
+			{Status: StatusNew, Label: "Новая", Transitions: []IdeaStatus{StatusReviewed, StatusAccepted, StatusRejected}},
+			{Status: StatusReviewed, Label: "Рассмотрена", Transitions: []IdeaStatus{StatusAccepted, StatusRejected}},
+			{Status: StatusAccepted, Label: "Принята", Transitions: []IdeaStatus{StatusInProgress, StatusRejected}},
+			{Status: StatusRejected, Label: "Отклонена", CommentRequired: true, Terminal: true},
+			{Status: StatusInProgress, Label: "В работе", Transitions: []IdeaStatus{StatusImplemented, StatusAccepted}},
+			{Status: StatusImplemented, Label: "Реализована", Terminal: true},
+		},
+	}
+}
+
+var workflow atomic.Pointer[Workflow]
+
+func init() {
+	workflow.Store(DefaultWorkflow())
+}
+
+// CurrentWorkflow returns the active workflow
+func CurrentWorkflow() *Workflow {
+	return workflow.Load()
+}
+
+// SetWorkflow makes a validated workflow active
+func SetWorkflow(w *Workflow) {
+	workflow.Store(w)
+}
+
+// State returns the state of a status, nil for statuses the workflow doesn't know
+func (w *Workflow) State(status IdeaStatus) *WorkflowState {
+	for i := range w.States {
+		if w.States[i].Status == status {
+			return &w.States[i]
+		}
+	}
+	return nil
+}
+
+// Statuses returns all statuses in workflow order
+func (w *Workflow) Statuses() []IdeaStatus {
+	statuses := make([]IdeaStatus, len(w.States))
+	for i, s := range w.States {
+		statuses[i] = s.Status
+	}
+	return statuses
+}
+
+// OpenStatuses returns the statuses that are not terminal
+func (w *Workflow) OpenStatuses() []IdeaStatus {
+	var statuses []IdeaStatus
+	for _, s := range w.States {
+		if !s.Terminal {
+			statuses = append(statuses, s.Status)
+		}
+	}
+	return statuses
+}
+
+// CanTransition reports whether an idea can move from one status to another.
+// Ideas in a status the workflow no longer knows can move to any status.
+func (w *Workflow) CanTransition(from, to IdeaStatus) bool {
+	if w.State(to) == nil {
+		return false
+	}
+	state := w.State(from)
+	if state == nil {
+		return from != to
+	}
+	for _, t := range state.Transitions {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the states an idea in a status can move to
+func (w *Workflow) Next(from IdeaStatus) []WorkflowState {
+	var next []WorkflowState
+	for _, s := range w.States {
+		if w.CanTransition(from, s.Status) {
+			next = append(next, s)
+		}
+	}
+	return next
+}
+
+// Validate checks that the workflow is consistent: statuses are unique, transitions
+// lead to known statuses, terminal states have no transitions and other states have some
+func (w *Workflow) Validate() error {
+	if len(w.States) == 0 {
+		return fmt.Errorf("workflow has no states")
+	}
+
+	seen := make(map[IdeaStatus]bool)
+	for _, s := range w.States {
+		if s.Status == "" {
+			return fmt.Errorf("state without status")
+		}
+		if seen[s.Status] {
+			return fmt.Errorf("status %q is defined twice", s.Status)
+		}
+		seen[s.Status] = true
+	}
+
+	for _, s := range w.States {
+		if s.Terminal && len(s.Transitions) > 0 {
+			return fmt.Errorf("terminal status %q has transitions", s.Status)
+		}
+		if !s.Terminal && len(s.Transitions) == 0 {
+			return fmt.Errorf("status %q has no transitions, mark it terminal", s.Status)
+		}
+		for _, t := range s.Transitions {
+			if !seen[t] {
+				return fmt.Errorf("status %q has a transition to unknown status %q", s.Status, t)
+			}
+			if t == s.Status {
+				return fmt.Errorf("status %q has a transition to itself", s.Status)
+			}
+		}
+	}
+
+	initial := w.State(w.Initial)
+	if initial == nil {
+		return fmt.Errorf("initial status %q is not defined", w.Initial)
+	}
+	if initial.Terminal {
+		return fmt.Errorf("initial status %q is terminal", w.Initial)
+	}
+	return nil
+}
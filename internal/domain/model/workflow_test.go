@@ -0,0 +1,147 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wf      *Workflow
+		wantErr bool
+	}{
+		{name: "default", wf: DefaultWorkflow()},
+		{name: "no states", wf: &Workflow{Initial: StatusNew}, wantErr: true},
+		{
+			name: "state without status",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate status",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Status: StatusImplemented, Terminal: true},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "terminal status with transitions",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Status: StatusImplemented, Terminal: true, Transitions: []IdeaStatus{StatusNew}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "open status without transitions",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Status: StatusReviewed},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "transition to unknown status",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusAccepted}},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "transition to itself",
+			wf: &Workflow{Initial: StatusNew, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusNew, StatusImplemented}},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown initial status",
+			wf: &Workflow{Initial: StatusReviewed, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "terminal initial status",
+			wf: &Workflow{Initial: StatusImplemented, States: []WorkflowState{
+				{Status: StatusNew, Transitions: []IdeaStatus{StatusImplemented}},
+				{Status: StatusImplemented, Terminal: true},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.wf.Validate()
+			if tt.wantErr && err == nil {
+				t.Errorf("Validate() = nil, want an error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate(): %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkflowCanTransition(t *testing.T) {
+	wf := DefaultWorkflow()
+
+	tests := []struct {
+		from, to IdeaStatus
+		want     bool
+	}{
+		{from: StatusNew, to: StatusReviewed, want: true},
+		{from: StatusNew, to: StatusRejected, want: true},
+		{from: StatusNew, to: StatusInProgress, want: false},
+		{from: StatusNew, to: StatusNew, want: false},
+		{from: StatusInProgress, to: StatusAccepted, want: true},
+		{from: StatusRejected, to: StatusNew, want: false},
+		{from: StatusImplemented, to: StatusInProgress, want: false},
+		{from: StatusNew, to: "archived", want: false},
+		// Ideas left in a status removed from the workflow can move anywhere
+		{from: "archived", to: StatusNew, want: true},
+		{from: "archived", to: "archived", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := wf.CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWorkflowNext(t *testing.T) {
+	wf := DefaultWorkflow()
+
+	tests := []struct {
+		from IdeaStatus
+		want []IdeaStatus
+	}{
+		{from: StatusNew, want: []IdeaStatus{StatusReviewed, StatusAccepted, StatusRejected}},
+		{from: StatusAccepted, want: []IdeaStatus{StatusRejected, StatusInProgress}},
+		{from: StatusInProgress, want: []IdeaStatus{StatusAccepted, StatusImplemented}},
+		{from: StatusRejected, want: nil},
+		{from: "archived", want: wf.Statuses()},
+	}
+
+	for _, tt := range tests {
+		var got []IdeaStatus
+		for _, s := range wf.Next(tt.from) {
+			got = append(got, s.Status)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Next(%s) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
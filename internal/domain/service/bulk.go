@@ -0,0 +1,136 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// Bulk actions check every idea first and report the ones they can't apply to as
+// failures; the rest are changed in a single transaction. A non-zero projectID
+// limits the action to the ideas of that project.
+
+// BulkSelect returns the ideas a bulk action may apply to as succeeded, and the ones
+// that don't exist, belong to another project or were merged into another idea as failed
+func (s *IdeaService) BulkSelect(ids []int64, projectID int64) (*model.BulkResult, error) {
+	result := &model.BulkResult{}
+	ideas, err := s.bulkTargets(ids, projectID, result)
+	if err != nil {
+		return nil, err
+	}
+	for _, idea := range ideas {
+		result.Succeeded = append(result.Succeeded, idea.ID)
+	}
+	return result, nil
+}
+
+// BulkUpdateStatus moves ideas to a status; ideas the workflow doesn't allow to move are skipped
+func (s *IdeaService) BulkUpdateStatus(ids []int64, status model.IdeaStatus, comment string, projectID int64) (*model.BulkResult, error) {
+	result := &model.BulkResult{}
+	ideas, err := s.bulkTargets(ids, projectID, result)
+	if err != nil {
+		return nil, err
+	}
+
+	comment = strings.TrimSpace(comment)
+	var valid []int64
+	for _, idea := range ideas {
+		if err := checkTransition(idea, status, comment); err != nil {
+			result.Fail(idea.ID, err.Error())
+			continue
+		}
+		valid = append(valid, idea.ID)
+	}
+
+	if len(valid) > 0 {
+		if err := s.repo.BulkUpdateStatus(valid, status, comment); err != nil {
+			return nil, err
+		}
+	}
+	result.Succeeded = valid
+	return result, nil
+}
+
+// BulkAssign makes a Telegram user responsible for ideas, an empty username unassigns them
+func (s *IdeaService) BulkAssign(ids []int64, username string, projectID int64) (*model.BulkResult, error) {
+	username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+
+	result, err := s.BulkSelect(ids, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Succeeded) > 0 {
+		if err := s.repo.BulkUpdateAssignee(result.Succeeded, username); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// BulkTag tags ideas, creating the tag when it doesn't exist yet
+func (s *IdeaService) BulkTag(ids []int64, name string, projectID int64) (*model.BulkResult, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.BulkSelect(ids, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Succeeded) > 0 {
+		if err := s.tags.AddToIdeas(result.Succeeded, name, model.DefaultTagColor(name)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// BulkDelete removes ideas together with their attachments
+func (s *IdeaService) BulkDelete(ids []int64, projectID int64) (*model.BulkResult, error) {
+	result, err := s.BulkSelect(ids, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Succeeded) > 0 {
+		if err := s.deleteIdeas(result.Succeeded); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// bulkTargets loads the ideas of a bulk action, skipping duplicate IDs
+func (s *IdeaService) bulkTargets(ids []int64, projectID int64, result *model.BulkResult) ([]*model.Idea, error) {
+	seen := make(map[int64]bool)
+	var ideas []*model.Idea
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		idea, err := s.repo.GetByID(id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && projectID != 0 && idea.ProjectID != projectID) {
+			result.Fail(id, "Идея не найдена")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Merged duplicates are hidden, changes go to the idea they were merged into
+		if idea.MergedIntoID != 0 {
+			result.Fail(id, fmt.Sprintf("Идея объединена с #%d", idea.MergedIntoID))
+			continue
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, nil
+}
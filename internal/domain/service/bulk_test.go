@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+func TestBulkSkipsMergedIdeas(t *testing.T) {
+	initStorage(t)
+
+	repo := storage.NewIdeaRepository()
+	ideaService := service.NewIdeaService()
+
+	var ids []int64
+	for _, text := range []string{"Тёмная тема", "Ночной режим", "Экспорт в PDF"} {
+		idea, err := repo.Create(model.CreateIdeaInput{TelegramChatID: -100, TelegramUserID: 1, RawText: text})
+		if err != nil {
+			t.Fatalf("create idea: %v", err)
+		}
+		ids = append(ids, idea.ID)
+	}
+	kept, duplicate, other := ids[0], ids[1], ids[2]
+	if err := repo.Merge(duplicate, kept); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	result, err := ideaService.BulkDelete([]int64{duplicate, other, 999}, 0)
+	if err != nil {
+		t.Fatalf("BulkDelete: %v", err)
+	}
+	if !slices.Equal(result.Succeeded, []int64{other}) {
+		t.Errorf("succeeded = %v, want [%d]", result.Succeeded, other)
+	}
+	want := []model.BulkFailure{
+		{IdeaID: duplicate, Reason: fmt.Sprintf("Идея объединена с #%d", kept)},
+		{IdeaID: 999, Reason: "Идея не найдена"},
+	}
+	if !slices.Equal(result.Failed, want) {
+		t.Errorf("failed = %v, want %v", result.Failed, want)
+	}
+
+	if _, err := repo.GetByID(duplicate); err != nil {
+		t.Errorf("merged idea was deleted: %v", err)
+	}
+	if _, err := repo.GetByID(other); err == nil {
+		t.Errorf("idea %d was not deleted", other)
+	}
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -88,9 +91,35 @@ const responseSchema = `{
       "type": "array",
       "items": {"type": "string"},
       "description": "Potential risks and implementation challenges"
+    },
+    "tags": {
+      "type": "array",
+      "items": {"type": "string"},
+      "description": "Existing tags listed with the idea that fit it; never invent new tags, leave empty when none fit or no tags are listed"
+    },
+    "scoring": {
+      "type": "object",
+      "description": "RICE estimate for prioritization; effort is derived from complexity",
+      "properties": {
+        "reach": {
+          "type": "integer",
+          "description": "How many users the idea affects per quarter, a rough estimate"
+        },
+        "impact": {
+          "type": "number",
+          "enum": [0.25, 0.5, 1, 2, 3],
+          "description": "Impact on each affected user: 3 massive, 2 high, 1 medium, 0.5 low, 0.25 minimal"
+        },
+        "confidence": {
+          "type": "integer",
+          "enum": [50, 80, 100],
+          "description": "Confidence in the reach and impact estimates, percent: 100 high, 80 medium, 50 low"
+        }
+      },
+      "required": ["reach", "impact", "confidence"]
     }
   },
-  "required": ["title", "summary", "detailed_description", "category", "priority", "complexity", "user_story", "acceptance_criteria"]
+  "required": ["title", "summary", "detailed_description", "category", "priority", "complexity", "user_story", "acceptance_criteria", "scoring"]
 }`
 
 type ClaudeService struct {
@@ -122,38 +151,98 @@ func NewClaudeService() *ClaudeService {
 	}
 }
 
-// EnrichIdea sends the raw idea to Claude and returns structured analysis
-func (s *ClaudeService) EnrichIdea(ctx context.Context, rawIdea string, username string) (*model.EnrichedIdea, error) {
+// Model returns the Claude model used for enrichment
+func (s *ClaudeService) Model() string {
+	return s.model
+}
+
+// PromptHash identifies the enrichment prompt of a project (nil for ideas without one):
+// it changes whenever the system prompt, the project's component catalog or the response schema change
+func (s *ClaudeService) PromptHash(project *model.Project) string {
+	sum := sha256.Sum256([]byte(s.enrichPrompt(project) + "\n" + responseSchema))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// enrichPrompt returns the system prompt for enriching ideas of a project:
+// the project's own prompt when set, followed by its component catalog
+func (s *ClaudeService) enrichPrompt(project *model.Project) string {
+	if project == nil {
+		return s.systemPrompt
+	}
+
+	prompt := s.systemPrompt
+	if project.SystemPrompt != "" {
+		prompt = project.SystemPrompt
+	}
+	if len(project.Components) > 0 {
+		prompt += "\n\n## Components of the " + project.Name + " project\n\n"
+		for _, c := range project.Components {
+			prompt += "- " + c.Name
+			if len(c.Aliases) > 0 {
+				prompt += " (also called " + strings.Join(c.Aliases, ", ") + ")"
+			}
+			if c.Description != "" {
+				prompt += ": " + c.Description
+			}
+			prompt += "\n"
+		}
+		prompt += "\nList affected_components only by the names above. Name a component outside " +
+			"the list only when the idea clearly needs one that does not exist yet."
+	}
+	return prompt
+}
+
+// EnrichIdea sends the raw idea, the author's answers to clarifying questions, the names
+// of existing tags and attached images to Claude and returns structured analysis.
+// The system prompt is the project's one when the idea belongs to a project.
+func (s *ClaudeService) EnrichIdea(ctx context.Context, project *model.Project, rawIdea string, username string, clarifications []model.Clarification, tags []string, images []model.Image) (*model.EnrichedIdea, error) {
 	userPrompt := fmt.Sprintf(`User @%s submitted an idea:
 
 "%s"
+`, username, rawIdea)
+
+	if len(clarifications) > 0 {
+		userPrompt += "\nThe author answered clarifying questions:\n"
+		for _, c := range clarifications {
+			userPrompt += fmt.Sprintf("\nQ: %s\nA: %s\n", c.Question, c.Answer)
+		}
+	}
+
+	// Tags change often, so they go with the idea rather than into the versioned system prompt
+	if len(tags) > 0 {
+		userPrompt += "\nExisting tags: " + strings.Join(tags, ", ") + "\n"
+	}
 
+	if len(images) > 0 {
+		userPrompt += fmt.Sprintf("\nThe author attached %d image(s), shown above. Use them to understand the idea.\n", len(images))
+	}
+
+	userPrompt += `
 Analyze this idea and return a structured JSON according to the schema.
-Do not use markdown formatting, return only clean JSON.`, username, rawIdea)
+Do not use markdown formatting, return only clean JSON.`
+
+	// Images go before the text, as recommended for vision prompts
+	var blocks []anthropic.ContentBlockParamUnion
+	for _, img := range images {
+		blocks = append(blocks, anthropic.NewImageBlockBase64(img.MediaType, base64.StdEncoding.EncodeToString(img.Data)))
+	}
+	blocks = append(blocks, anthropic.NewTextBlock(userPrompt))
 
 	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
 		Model:     s.model,
 		MaxTokens: 2000,
 		System: []anthropic.TextBlockParam{
-			{Text: s.systemPrompt + "\n\nExpected JSON schema:\n" + responseSchema},
+			{Text: s.enrichPrompt(project) + "\n\nExpected JSON schema:\n" + responseSchema},
 		},
 		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
+			anthropic.NewUserMessage(blocks...),
 		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("claude API error: %w", err)
 	}
 
-	// Extract text from response
-	var responseText string
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			responseText = block.Text
-			break
-		}
-	}
-
+	responseText := extractText(message)
 	if responseText == "" {
 		return nil, fmt.Errorf("empty response from Claude")
 	}
@@ -167,6 +256,65 @@ Do not use markdown formatting, return only clean JSON.`, username, rawIdea)
 	return &enriched, nil
 }
 
+// clarifyResult represents Claude's decision on whether an idea needs clarification
+type clarifyResult struct {
+	NeedsClarification bool     `json:"needs_clarification"`
+	Questions          []string `json:"questions"`
+}
+
+// ClarifyingQuestions returns up to maxQuestions questions to ask the author when the idea
+// is too vague to enrich well, or nothing when it is clear enough
+func (s *ClaudeService) ClarifyingQuestions(ctx context.Context, rawIdea string, maxQuestions int) ([]string, error) {
+	prompt := fmt.Sprintf(`A team member submitted a product idea:
+
+"%s"
+
+Decide whether the idea is too vague to turn into a concrete feature description with
+acceptance criteria. Ideas like "better search" or "improve onboarding" are vague; ideas
+that say what should change and for whom are clear enough.
+
+If it is vague, write at most %d short questions to the author that would remove the
+ambiguity (who is the user, what exactly should change, what problem it solves).
+Ask only what really matters, fewer questions are better.
+
+Return JSON:
+- needs_clarification: true if the idea is vague
+- questions: array of questions (empty if needs_clarification is false)
+
+Write the questions in the same language as the idea.
+Return ONLY JSON without markdown.`, rawIdea, maxQuestions)
+
+	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     s.model,
+		MaxTokens: 500,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+
+	responseText := extractText(message)
+	if responseText == "" {
+		return nil, nil
+	}
+
+	var result clarifyResult
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		// If parsing fails, enrich without clarification
+		return nil, nil
+	}
+
+	if !result.NeedsClarification {
+		return nil, nil
+	}
+	if len(result.Questions) > maxQuestions {
+		result.Questions = result.Questions[:maxQuestions]
+	}
+	return result.Questions, nil
+}
+
 // FormatEnrichedForTelegram formats the enriched idea for Telegram message
 func FormatEnrichedForTelegram(enriched *model.EnrichedIdea) string {
 	msg := fmt.Sprintf("✨ *%s*\n\n", escapeMarkdown(enriched.Title))
@@ -275,23 +423,218 @@ func (s *ClaudeService) CheckDuplicate(ctx context.Context, newIdea string, exis
 		return nil, fmt.Errorf("claude API error: %w", err)
 	}
 
-	var responseText string
+	responseText := extractText(message)
+	if responseText == "" {
+		return &DuplicateResult{IsDuplicate: false}, nil
+	}
+
+	var result DuplicateResult
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		// If parsing fails, assume not a duplicate
+		return &DuplicateResult{IsDuplicate: false}, nil
+	}
+
+	return &result, nil
+}
+
+// extractText returns the first text block of a Claude response
+func extractText(message *anthropic.Message) string {
 	for _, block := range message.Content {
 		if block.Type == "text" {
-			responseText = block.Text
-			break
+			return block.Text
 		}
 	}
+	return ""
+}
 
+// ThemeAssignment assigns an idea to a theme by name
+type ThemeAssignment struct {
+	IdeaID int64  `json:"idea_id"`
+	Theme  string `json:"theme"`
+}
+
+// NewTheme is a theme proposed by Claude during clustering
+type NewTheme struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ClusterResult represents the result of clustering ideas into themes
+type ClusterResult struct {
+	NewThemes   []NewTheme        `json:"new_themes"`
+	Assignments []ThemeAssignment `json:"assignments"`
+}
+
+// ClusterIdeas assigns ideas to existing themes or proposes new ones
+func (s *ClaudeService) ClusterIdeas(ctx context.Context, themes []*model.Theme, ideas []model.IdeaSummary) (*ClusterResult, error) {
+	var themesList strings.Builder
+	for _, t := range themes {
+		themesList.WriteString(fmt.Sprintf("- %s: %s\n", t.Name, t.Description))
+	}
+	if themesList.Len() == 0 {
+		themesList.WriteString("(no themes yet)\n")
+	}
+
+	var ideasList strings.Builder
+	for _, idea := range ideas {
+		text := idea.Title
+		if text == "" {
+			text = idea.RawText
+		}
+		ideasList.WriteString(fmt.Sprintf("- ID %d: %s\n", idea.ID, truncateRunes(text, 300)))
+	}
+
+	prompt := fmt.Sprintf(`You are grouping a backlog of product ideas into themes.
+
+Existing themes:
+%s
+Ideas to assign:
+%s
+Assign every idea to exactly one theme. Prefer existing themes; only propose a new theme
+when no existing theme fits. Theme names must be short (2-5 words) and written in the same
+language as the ideas. Use the exact name of an existing theme when assigning to it.
+
+Return JSON:
+- new_themes: array of {"name", "description"} for themes that don't exist yet (description is one sentence)
+- assignments: array of {"idea_id", "theme"} covering every idea above
+
+Return ONLY JSON without markdown.`, themesList.String(), ideasList.String())
+
+	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     s.model,
+		MaxTokens: 4000,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+
+	responseText := extractText(message)
 	if responseText == "" {
-		return &DuplicateResult{IsDuplicate: false}, nil
+		return nil, fmt.Errorf("empty response from Claude")
 	}
 
-	var result DuplicateResult
+	var result ClusterResult
 	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		// If parsing fails, assume not a duplicate
-		return &DuplicateResult{IsDuplicate: false}, nil
+		return nil, fmt.Errorf("failed to parse Claude response as JSON: %w\nResponse: %s", err, responseText)
 	}
 
 	return &result, nil
 }
+
+// maxBreakdownItems caps the number of parts an epic is broken down into
+const maxBreakdownItems = 8
+
+// BreakDownEpic proposes smaller ideas an epic could be delivered as, each one
+// useful on its own
+func (s *ClaudeService) BreakDownEpic(ctx context.Context, idea *model.Idea) ([]model.BreakdownItem, error) {
+	var details strings.Builder
+	details.WriteString(idea.RawText)
+	if e := idea.Enriched; e != nil {
+		details.WriteString("\n\nTitle: " + e.Title)
+		if e.DetailedDesc != "" {
+			details.WriteString("\nDescription: " + e.DetailedDesc)
+		}
+		if len(e.AcceptanceCriteria) > 0 {
+			details.WriteString("\nAcceptance criteria:\n- " + strings.Join(e.AcceptanceCriteria, "\n- "))
+		}
+		if len(e.AffectedComponents) > 0 {
+			details.WriteString("\nAffected components: " + strings.Join(e.AffectedComponents, ", "))
+		}
+	}
+
+	prompt := fmt.Sprintf(`This product idea is too large to be delivered at once:
+
+%s
+
+Break it down into 2-%d smaller ideas that can be built and shipped one by one.
+Every part must be useful on its own, not a technical step like "create the database table".
+Order them so the most valuable part comes first.
+
+Return JSON:
+- items: array of {"title", "description"}; title is short, description is 1-3 sentences
+  saying what changes for the user
+
+Write in the same language as the idea.
+Return ONLY JSON without markdown.`, details.String(), maxBreakdownItems)
+
+	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     s.model,
+		MaxTokens: 2000,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+
+	responseText := extractText(message)
+	if responseText == "" {
+		return nil, fmt.Errorf("empty response from Claude")
+	}
+
+	var result struct {
+		Items []model.BreakdownItem `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse Claude response as JSON: %w\nResponse: %s", err, responseText)
+	}
+
+	if len(result.Items) > maxBreakdownItems {
+		result.Items = result.Items[:maxBreakdownItems]
+	}
+	return result.Items, nil
+}
+
+// AnswerQuestion answers a question about the backlog using only the given ideas,
+// citing them as #ID
+func (s *ClaudeService) AnswerQuestion(ctx context.Context, question string, ideas []*model.Idea) (string, error) {
+	var ideasList strings.Builder
+	for _, idea := range ideas {
+		ideasList.WriteString(fmt.Sprintf("### #%d", idea.ID))
+		if idea.Title != "" {
+			ideasList.WriteString(" " + idea.Title)
+		}
+		ideasList.WriteString(fmt.Sprintf("\nStatus: %s\n", idea.Status))
+		if idea.Enriched != nil && idea.Enriched.Summary != "" {
+			ideasList.WriteString("Summary: " + idea.Enriched.Summary + "\n")
+		}
+		ideasList.WriteString("Original text: " + truncateRunes(idea.RawText, 1000) + "\n\n")
+	}
+
+	prompt := fmt.Sprintf(`Answer a question about the product idea backlog.
+
+Question:
+"%s"
+
+Ideas from the backlog that may be relevant:
+
+%s
+Rules:
+- Use ONLY the ideas above. Do not use outside knowledge and do not invent ideas.
+- Cite every idea you rely on as #ID (for example #12).
+- If none of the ideas answer the question, say that nothing like this has been suggested yet.
+- Be concise: a short paragraph or a few bullet points.
+- Answer in the same language as the question, in plain text without markdown.`, question, ideasList.String())
+
+	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     s.model,
+		MaxTokens: 1000,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("claude API error: %w", err)
+	}
+
+	responseText := extractText(message)
+	if responseText == "" {
+		return "", fmt.Errorf("empty response from Claude")
+	}
+
+	return strings.TrimSpace(responseText), nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/mailer"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+//go:embed templates/*
+var templatesFS embed.FS
+
+// digestPeriod is the length of the window covered by a digest
+const digestPeriod = 7 * 24 * time.Hour
+
+// topVotedLimit is the number of most voted ideas listed in a digest
+const topVotedLimit = 5
+
+type DigestService struct {
+	repo       *storage.IdeaRepository
+	votes      *storage.VoteRepository
+	mailer     *mailer.Mailer
+	recipients []string
+	staleDays  int
+	baseURL    string
+	htmlTmpl   *htmltemplate.Template
+	textTmpl   *texttemplate.Template
+}
+
+func NewDigestService() (*DigestService, error) {
+	cfg := config.Get()
+
+	funcs := map[string]interface{}{
+		"formatDate": func(t time.Time) string {
+			return t.Format("02.01.2006")
+		},
+		"ideaTitle": ideaTitle,
+	}
+
+	htmlTmpl, err := htmltemplate.New("digest.html").Funcs(funcs).ParseFS(templatesFS, "templates/digest.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse digest html template: %w", err)
+	}
+	textTmpl, err := texttemplate.New("digest.txt").Funcs(funcs).ParseFS(templatesFS, "templates/digest.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse digest text template: %w", err)
+	}
+
+	return &DigestService{
+		repo:       storage.NewIdeaRepository(),
+		votes:      storage.NewVoteRepository(),
+		mailer:     mailer.NewMailer(),
+		recipients: cfg.Digest.EmailRecipients,
+		staleDays:  cfg.Digest.StaleDays,
+		baseURL:    cfg.Web.BaseURL,
+		htmlTmpl:   htmlTmpl,
+		textTmpl:   textTmpl,
+	}, nil
+}
+
+// Recipients returns the configured email recipients
+func (s *DigestService) Recipients() []string {
+	return s.recipients
+}
+
+// EmailEnabled reports whether digest emails can be sent
+func (s *DigestService) EmailEnabled() bool {
+	return s.mailer.Enabled() && len(s.recipients) > 0
+}
+
+// Build collects new ideas, the most voted ideas, status changes and stale ideas for the period [from, to).
+// A non-zero chatID limits the digest to ideas from that Telegram chat.
+func (s *DigestService) Build(from, to time.Time, chatID int64) (*model.Digest, error) {
+	digest := &model.Digest{
+		From:      from,
+		To:        to,
+		BaseURL:   s.baseURL,
+		StaleDays: s.staleDays,
+	}
+
+	newIdeas, err := s.repo.List(model.IdeaFilter{TelegramChatID: chatID, CreatedFrom: from, CreatedTo: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list new ideas: %w", err)
+	}
+	digest.NewCount = len(newIdeas)
+	digest.NewIdeas = groupByCategory(newIdeas)
+
+	digest.TopVoted, err = s.votes.TopVoted(from, to, chatID, topVotedLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top voted ideas: %w", err)
+	}
+
+	digest.StatusChanges, err = s.repo.ListStatusChanges(from, to, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status changes: %w", err)
+	}
+
+	counts, err := s.repo.CountByStatus(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ideas by status: %w", err)
+	}
+	for _, status := range model.AllStatuses() {
+		digest.StatusCounts = append(digest.StatusCounts, model.StatusCount{Status: status, Count: counts[status]})
+	}
+
+	if s.staleDays > 0 {
+		digest.StaleIdeas, err = s.repo.List(model.IdeaFilter{
+			Status:         model.CurrentWorkflow().OpenStatuses(),
+			TelegramChatID: chatID,
+			UpdatedBefore:  to.AddDate(0, 0, -s.staleDays),
+			Limit:          20,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list stale ideas: %w", err)
+		}
+	}
+
+	return digest, nil
+}
+
+// BuildLatest builds a digest for the last week.
+// A non-zero chatID limits the digest to ideas from that Telegram chat.
+func (s *DigestService) BuildLatest(chatID int64) (*model.Digest, error) {
+	now := time.Now()
+	return s.Build(now.Add(-digestPeriod), now, chatID)
+}
+
+// RenderHTML renders the HTML version of the digest email
+func (s *DigestService) RenderHTML(digest *model.Digest) (string, error) {
+	var buf bytes.Buffer
+	if err := s.htmlTmpl.Execute(&buf, digest); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderText renders the plaintext version of the digest email
+func (s *DigestService) RenderText(digest *model.Digest) (string, error) {
+	var buf bytes.Buffer
+	if err := s.textTmpl.Execute(&buf, digest); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SendEmail builds the weekly digest and sends it to the configured recipients
+func (s *DigestService) SendEmail(ctx context.Context) error {
+	if !s.EmailEnabled() {
+		return fmt.Errorf("digest email is not configured")
+	}
+
+	digest, err := s.BuildLatest(0)
+	if err != nil {
+		return err
+	}
+
+	htmlBody, err := s.RenderHTML(digest)
+	if err != nil {
+		return fmt.Errorf("failed to render html digest: %w", err)
+	}
+	textBody, err := s.RenderText(digest)
+	if err != nil {
+		return fmt.Errorf("failed to render text digest: %w", err)
+	}
+
+	subject := fmt.Sprintf("Дайджест идей за %s – %s", digest.From.Format("02.01"), digest.To.Format("02.01.2006"))
+	if err := s.mailer.Send(ctx, s.recipients, subject, textBody, htmlBody); err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+
+	log.Printf("Digest email sent to %d recipients (%d new ideas, %d status changes)",
+		len(s.recipients), digest.NewCount, len(digest.StatusChanges))
+	return nil
+}
+
+// FormatDigestForTelegram formats the digest as a Telegram MarkdownV2 message,
+// dates are shown in the given timezone
+func FormatDigestForTelegram(digest *model.Digest, loc *time.Location) string {
+	msg := fmt.Sprintf("📊 *Дайджест идей за %s – %s*\n\n",
+		escapeMarkdown(digest.From.In(loc).Format("02.01")), escapeMarkdown(digest.To.In(loc).Format("02.01.2006")))
+
+	msg += "*Статусы:*\n"
+	for _, sc := range digest.StatusCounts {
+		msg += fmt.Sprintf("• %s: %d\n", escapeMarkdown(sc.Status.Label()), sc.Count)
+	}
+
+	msg += fmt.Sprintf("\n💡 Новых идей за неделю: %d\n", digest.NewCount)
+
+	if len(digest.TopVoted) > 0 {
+		msg += "\n👍 *Больше всего голосов:*\n"
+		for _, v := range digest.TopVoted {
+			url := fmt.Sprintf("%s/ideas/%d", digest.BaseURL, v.IdeaID)
+			msg += fmt.Sprintf("• [\\#%d](%s) %s \\(\\+%d\\)\n", v.IdeaID, escapeMarkdown(url), escapeMarkdown(truncateRunes(v.IdeaTitle, 80)), v.Votes)
+		}
+	}
+
+	sections := []struct {
+		title  string
+		status model.IdeaStatus
+	}{
+		{"✅ *Приняты:*", model.StatusAccepted},
+		{"🚀 *Реализованы:*", model.StatusImplemented},
+	}
+	for _, section := range sections {
+		changes := digest.ChangesTo(section.status)
+		if len(changes) == 0 {
+			continue
+		}
+		msg += "\n" + section.title + "\n"
+		for _, c := range changes {
+			url := fmt.Sprintf("%s/ideas/%d", digest.BaseURL, c.IdeaID)
+			msg += fmt.Sprintf("• [\\#%d](%s) %s\n", c.IdeaID, escapeMarkdown(url), escapeMarkdown(truncateRunes(c.IdeaTitle, 80)))
+		}
+	}
+
+	msg += fmt.Sprintf("\n👉 [Все идеи](%s)", escapeMarkdown(digest.BaseURL+"/ideas"))
+	return msg
+}
+
+// truncateRunes truncates a string to n runes, adding an ellipsis when cut
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// groupByCategory groups ideas by category in the order of AllCategories,
+// ideas without a category go last
+func groupByCategory(ideas []*model.Idea) []model.CategoryIdeas {
+	byCategory := make(map[model.IdeaCategory][]*model.Idea)
+	for _, idea := range ideas {
+		byCategory[idea.Category] = append(byCategory[idea.Category], idea)
+	}
+
+	var groups []model.CategoryIdeas
+	for _, c := range append(model.AllCategories(), "") {
+		if len(byCategory[c]) > 0 {
+			groups = append(groups, model.CategoryIdeas{Category: c, Ideas: byCategory[c]})
+		}
+	}
+	return groups
+}
+
+// ideaTitle returns the idea title or a truncated raw text when it hasn't been enriched
+func ideaTitle(idea *model.Idea) string {
+	if idea.Title != "" {
+		return idea.Title
+	}
+	return truncateRunes(idea.RawText, 80)
+}
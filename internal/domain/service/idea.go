@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,21 +18,54 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// Claude accepts up to 5MB per image; a few screenshots are enough for the analysis
+const (
+	maxVisionImages    = 5
+	maxVisionImageSize = 5 << 20
+)
+
 type IdeaService struct {
 	repo          *storage.IdeaRepository
+	attachments   *storage.AttachmentRepository
+	projects      *storage.ProjectRepository
+	tags          *storage.TagRepository
+	votes         *storage.VoteRepository
+	links         *storage.LinkRepository
 	claudeService *ClaudeService
 	rateLimiter   *RateLimiter
+	onCreated     []func(*model.Idea)
+	onMerged      []func(idea, into *model.Idea)
+
+	// Projects with their own limits get separate limiters
+	projectLimiters map[int64]*projectLimiter
+	limitersMu      sync.Mutex
+}
+
+type projectLimiter struct {
+	perUser, global int
+	limiter         *RateLimiter
 }
 
 func NewIdeaService() *IdeaService {
 	cfg := config.Get()
 	return &IdeaService{
-		repo:          storage.NewIdeaRepository(),
-		claudeService: NewClaudeService(),
-		rateLimiter:   NewRateLimiter(cfg.RateLimit.PerUser, cfg.RateLimit.Global),
+		repo:            storage.NewIdeaRepository(),
+		attachments:     storage.NewAttachmentRepository(),
+		projects:        storage.NewProjectRepository(),
+		tags:            storage.NewTagRepository(),
+		votes:           storage.NewVoteRepository(),
+		links:           storage.NewLinkRepository(),
+		claudeService:   NewClaudeService(),
+		rateLimiter:     NewRateLimiter(cfg.RateLimit.PerUser, cfg.RateLimit.Global),
+		projectLimiters: make(map[int64]*projectLimiter),
 	}
 }
 
+// OnCreated registers a hook that is called after a new idea has been saved and enriched
+func (s *IdeaService) OnCreated(hook func(*model.Idea)) {
+	s.onCreated = append(s.onCreated, hook)
+}
+
 // DuplicateError represents a duplicate idea error
 type DuplicateError struct {
 	SimilarID int64
@@ -42,15 +80,23 @@ func (e *DuplicateError) Error() string {
 func (s *IdeaService) CreateAndEnrich(ctx context.Context, input model.CreateIdeaInput) (*model.Idea, *model.EnrichedIdea, error) {
 	log.Printf("CreateAndEnrich called for user %d: %s", input.TelegramUserID, input.RawText[:min(50, len(input.RawText))])
 
+	project, err := s.projects.ForChat(input.TelegramChatID, input.MessageThreadID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve project: %w", err)
+	}
+	if project != nil {
+		input.ProjectID = project.ID
+	}
+
 	// Check rate limit
-	if !s.rateLimiter.Allow(input.TelegramUserID) {
+	if !s.limiterFor(project).Allow(input.TelegramUserID) {
 		log.Printf("Rate limit exceeded for user %d", input.TelegramUserID)
 		return nil, nil, fmt.Errorf("rate limit exceeded")
 	}
 
-	// Check for duplicates first
+	// Check for duplicates first, only among ideas of the same project
 	log.Printf("Checking for duplicate ideas...")
-	existingIdeas, err := s.repo.ListSummaries()
+	existingIdeas, err := s.repo.ListSummaries(input.ProjectID)
 	if err != nil {
 		log.Printf("Warning: failed to get existing ideas for duplicate check: %v", err)
 	} else if len(existingIdeas) > 0 {
@@ -75,32 +121,213 @@ func (s *IdeaService) CreateAndEnrich(ctx context.Context, input model.CreateIde
 	}
 	log.Printf("Idea created with ID %d", idea.ID)
 
+	var images []model.Image
+	for _, att := range input.Attachments {
+		if _, err := s.attachments.Create(ctx, idea.ID, att); err != nil {
+			log.Printf("Warning: failed to store attachment %q for idea %d: %v", att.FileName, idea.ID, err)
+		}
+		if model.IsVisionImage(att.ContentType) && len(att.Data) <= maxVisionImageSize && len(images) < maxVisionImages {
+			images = append(images, model.Image{MediaType: att.ContentType, Data: att.Data})
+		}
+	}
+
 	// Enrich with Claude
 	username := input.TelegramUsername
 	if username == "" {
 		username = input.TelegramFirstName
 	}
 
+	tags := s.tagNames()
+
 	log.Printf("Calling Claude API for idea %d...", idea.ID)
-	enriched, err := s.claudeService.EnrichIdea(ctx, input.RawText, username)
+	enriched, err := s.claudeService.EnrichIdea(ctx, project, input.RawText, username, input.Clarifications, tags, images)
 	if err != nil {
 		log.Printf("ERROR: failed to enrich idea %d: %v", idea.ID, err)
 		// Return the idea without enrichment - we'll try again later or manually
+		s.notifyCreated(idea)
 		return idea, nil, nil
 	}
 	log.Printf("Claude API returned successfully for idea %d", idea.ID)
+	if input.Category != "" {
+		enriched.Category = string(input.Category)
+	}
+	normalizeComponents(project, enriched)
+	enriched.Tags = knownTags(tags, enriched.Tags)
 
 	// Update the idea with enriched data
-	if err := s.repo.UpdateEnriched(idea.ID, enriched); err != nil {
+	if err := s.repo.ApplyEnrichment(idea.ID, enriched, s.claudeService.Model(), s.claudeService.PromptHash(project)); err != nil {
 		log.Printf("Warning: failed to save enriched data for idea %d: %v", idea.ID, err)
 	}
+	s.autoAssign(idea, project, enriched)
+	s.proposeScore(idea, enriched)
 
 	// Refresh the idea from DB
 	idea, _ = s.repo.GetByID(idea.ID)
 
+	s.notifyCreated(idea)
+
 	return idea, enriched, nil
 }
 
+func (s *IdeaService) notifyCreated(idea *model.Idea) {
+	if idea == nil {
+		return
+	}
+	for _, hook := range s.onCreated {
+		hook(idea)
+	}
+}
+
+// ClarifyingQuestions returns questions to ask the author before enrichment,
+// or nothing when clarification is disabled or the idea is clear enough
+func (s *IdeaService) ClarifyingQuestions(ctx context.Context, rawText string) ([]string, error) {
+	cfg := config.Get()
+	if !cfg.Clarify.Enabled || cfg.Clarify.MaxQuestions <= 0 {
+		return nil, nil
+	}
+	return s.claudeService.ClarifyingQuestions(ctx, rawText, cfg.Clarify.MaxQuestions)
+}
+
+// Reenrich runs enrichment again for an existing idea with the current prompt and model,
+// keeping the previous analysis as an older version
+func (s *IdeaService) Reenrich(ctx context.Context, id int64) (*model.Idea, error) {
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	username := idea.TelegramUsername
+	if username == "" {
+		username = idea.TelegramFirstName
+	}
+
+	images, err := s.loadImages(ctx, id)
+	if err != nil {
+		log.Printf("Warning: failed to load images of idea %d: %v", id, err)
+	}
+
+	project, err := s.projectOf(idea)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := s.tagNames()
+	enriched, err := s.claudeService.EnrichIdea(ctx, project, idea.RawText, username, idea.Clarifications, tags, images)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enrich idea %d: %w", id, err)
+	}
+	model.KeepOverrides(enriched, idea.Enriched, idea.OverriddenFields)
+	normalizeComponents(project, enriched)
+	enriched.Tags = knownTags(tags, enriched.Tags)
+
+	promptHash := s.claudeService.PromptHash(project)
+	if err := s.repo.ApplyEnrichment(id, enriched, s.claudeService.Model(), promptHash); err != nil {
+		return nil, fmt.Errorf("failed to save enrichment for idea %d: %w", id, err)
+	}
+	log.Printf("Idea %d re-enriched with %s (prompt %s)", id, s.claudeService.Model(), promptHash)
+	s.autoAssign(idea, project, enriched)
+	s.proposeScore(idea, enriched)
+
+	return s.repo.GetByID(id)
+}
+
+// ValidationError is returned when user input is rejected; the message is safe to show in the UI
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// UpdateEnriched saves a manually edited analysis. Fields that differ from the current
+// analysis are marked as overridden and survive later re-enrichment.
+func (s *IdeaService) UpdateEnriched(id int64, edited *model.EnrichedIdea) (*model.Idea, error) {
+	edited.Title = strings.TrimSpace(edited.Title)
+	if edited.Title == "" {
+		return nil, &ValidationError{Message: "Название не может быть пустым"}
+	}
+	if !model.IdeaCategory(edited.Category).IsValid() {
+		return nil, &ValidationError{Message: fmt.Sprintf("Неизвестная категория %q", edited.Category)}
+	}
+	if !model.IdeaPriority(edited.Priority).IsValid() {
+		return nil, &ValidationError{Message: fmt.Sprintf("Неизвестный приоритет %q", edited.Priority)}
+	}
+	if !model.IdeaComplexity(edited.Complexity).IsValid() {
+		return nil, &ValidationError{Message: fmt.Sprintf("Неизвестная сложность %q", edited.Complexity)}
+	}
+
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	current := idea.Enriched
+	if current == nil {
+		current = &model.EnrichedIdea{}
+	}
+
+	// Fields the form does not cover are carried over from the current analysis
+	edited.TechnicalNotes = current.TechnicalNotes
+	edited.RelatedFeatures = current.RelatedFeatures
+	edited.Scoring = current.Scoring
+	edited.Tags = current.Tags
+
+	project, err := s.projectOf(idea)
+	if err != nil {
+		return nil, err
+	}
+	normalizeComponents(project, edited)
+
+	changed := model.ChangedFields(current, edited)
+	if len(changed) == 0 {
+		return idea, nil
+	}
+
+	overridden := idea.OverriddenFields
+	for _, field := range changed {
+		if !idea.IsOverridden(field) {
+			overridden = append(overridden, field)
+		}
+	}
+
+	if err := s.repo.ApplyManualEdit(id, edited, overridden); err != nil {
+		return nil, fmt.Errorf("failed to save edited idea %d: %w", id, err)
+	}
+	s.autoAssign(idea, project, edited)
+	s.proposeScore(idea, edited)
+
+	return s.repo.GetByID(id)
+}
+
+// ClearOverrides lets the next re-enrichment replace manually edited fields again
+func (s *IdeaService) ClearOverrides(id int64) error {
+	return s.repo.ClearOverrides(id)
+}
+
+// ListEnrichmentVersions returns all enrichment versions of an idea, newest first
+func (s *IdeaService) ListEnrichmentVersions(id int64) ([]*model.EnrichmentVersion, error) {
+	return s.repo.ListEnrichmentVersions(id)
+}
+
+// GetEnrichmentVersion returns a single enrichment version of an idea
+func (s *IdeaService) GetEnrichmentVersion(id int64, version int) (*model.EnrichmentVersion, error) {
+	return s.repo.GetEnrichmentVersion(id, version)
+}
+
+// RollbackEnrichment makes an older enrichment version the active analysis of the idea
+func (s *IdeaService) RollbackEnrichment(id int64, version int) error {
+	if err := s.repo.ActivateEnrichmentVersion(id, version); err != nil {
+		return err
+	}
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	s.proposeScore(idea, idea.Enriched)
+	return nil
+}
+
 // GetByID retrieves an idea by ID
 func (s *IdeaService) GetByID(id int64) (*model.Idea, error) {
 	return s.repo.GetByID(id)
@@ -111,9 +338,77 @@ func (s *IdeaService) List(filter model.IdeaFilter) ([]*model.Idea, error) {
 	return s.repo.List(filter)
 }
 
-// UpdateStatus updates the status of an idea
-func (s *IdeaService) UpdateStatus(id int64, status model.IdeaStatus) error {
-	return s.repo.UpdateStatus(id, status)
+// ListPage retrieves one page of ideas matching the filter, starting at filter.Cursor
+func (s *IdeaService) ListPage(filter model.IdeaFilter) (*model.IdeaPage, error) {
+	page, err := s.repo.ListPage(filter)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		return nil, &ValidationError{Message: "Ссылка на страницу устарела, откройте список заново"}
+	}
+	return page, err
+}
+
+// Each calls fn for every idea matching the filter in its sort order, loading them
+// a page of filter.Limit at a time; it stops at the first error fn returns
+func (s *IdeaService) Each(filter model.IdeaFilter, fn func(*model.Idea) error) error {
+	for {
+		page, err := s.ListPage(filter)
+		if err != nil {
+			return err
+		}
+		for _, idea := range page.Ideas {
+			if err := fn(idea); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// UpdateStatus moves an idea to another status. The move must be allowed by the
+// workflow, and statuses that require a comment reject moves without one.
+func (s *IdeaService) UpdateStatus(id int64, status model.IdeaStatus, comment string) error {
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	comment = strings.TrimSpace(comment)
+	if err := checkTransition(idea, status, comment); err != nil {
+		return err
+	}
+	if idea.Status == status {
+		return nil
+	}
+
+	return s.repo.UpdateStatus(id, status, comment)
+}
+
+// checkTransition returns a ValidationError when the workflow does not allow moving
+// the idea to status with the given comment; staying in the same status is allowed
+func checkTransition(idea *model.Idea, status model.IdeaStatus, comment string) error {
+	workflow := model.CurrentWorkflow()
+	target := workflow.State(status)
+	if target == nil {
+		return &ValidationError{Message: fmt.Sprintf("Неизвестный статус %q", status)}
+	}
+	if idea.Status == status {
+		return nil
+	}
+	if !workflow.CanTransition(idea.Status, status) {
+		return &ValidationError{Message: fmt.Sprintf("Идею в статусе «%s» нельзя перевести в «%s»", idea.Status.Label(), status.Label())}
+	}
+	if target.CommentRequired && comment == "" {
+		return &ValidationError{Message: fmt.Sprintf("Для статуса «%s» нужен комментарий", status.Label())}
+	}
+	return nil
+}
+
+// StatusHistory returns the status changes of an idea, oldest first
+func (s *IdeaService) StatusHistory(id int64) ([]model.StatusChange, error) {
+	return s.repo.ListStatusHistory(id)
 }
 
 // UpdateAdminNotes updates the admin notes for an idea
@@ -123,7 +418,83 @@ func (s *IdeaService) UpdateAdminNotes(id int64, notes string) error {
 
 // Delete removes an idea
 func (s *IdeaService) Delete(id int64) error {
-	return s.repo.Delete(id)
+	return s.deleteIdeas([]int64{id})
+}
+
+// deleteIdeas deletes ideas and then the files of their attachments. The files are listed
+// before their rows go away, but only removed once the ideas are deleted, so a failed
+// delete doesn't leave ideas with missing attachments.
+func (s *IdeaService) deleteIdeas(ids []int64) error {
+	attachments := make(map[int64][]*model.Attachment)
+	if s.attachments.Enabled() {
+		for _, id := range ids {
+			list, err := s.attachments.ListByIdea(id)
+			if err != nil {
+				log.Printf("Warning: failed to list attachments of idea %d: %v", id, err)
+				continue
+			}
+			attachments[id] = list
+		}
+	}
+
+	if err := s.repo.BulkDelete(ids); err != nil {
+		return err
+	}
+
+	for id, list := range attachments {
+		if err := s.attachments.RemoveFiles(context.Background(), list); err != nil {
+			log.Printf("Warning: failed to delete attachment files of idea %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// ListAttachments returns the files attached to an idea
+func (s *IdeaService) ListAttachments(ideaID int64) ([]*model.Attachment, error) {
+	return s.attachments.ListByIdea(ideaID)
+}
+
+// OpenAttachment returns an attachment and its contents; the caller closes the reader
+func (s *IdeaService) OpenAttachment(ctx context.Context, id int64) (*model.Attachment, io.ReadCloser, error) {
+	a, err := s.attachments.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := s.attachments.Open(ctx, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, r, nil
+}
+
+// loadImages reads the image attachments of an idea for vision-aware enrichment
+func (s *IdeaService) loadImages(ctx context.Context, ideaID int64) ([]model.Image, error) {
+	if !s.attachments.Enabled() {
+		return nil, nil
+	}
+
+	attachments, err := s.attachments.ListByIdea(ideaID)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []model.Image
+	for _, a := range attachments {
+		if !a.IsImage() || a.Size > maxVisionImageSize || len(images) >= maxVisionImages {
+			continue
+		}
+		r, err := s.attachments.Open(ctx, a)
+		if err != nil {
+			return images, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return images, err
+		}
+		images = append(images, model.Image{MediaType: a.ContentType, Data: data})
+	}
+	return images, nil
 }
 
 // Count returns the total number of ideas
@@ -131,6 +502,286 @@ func (s *IdeaService) Count(filter model.IdeaFilter) (int, error) {
 	return s.repo.Count(filter)
 }
 
+// askSearchLimit is how many ideas are retrieved as context for a question
+const askSearchLimit = 15
+
+var citationRe = regexp.MustCompile(`#(\d+)`)
+
+// Ask answers a question about the backlog, grounded in ideas found by local search.
+// Only ideas that the answer actually cites are returned as sources.
+// A non-zero projectID limits the search to the project's ideas.
+func (s *IdeaService) Ask(ctx context.Context, question string, projectID int64) (*model.Answer, error) {
+	ideas, err := s.repo.Search(question, projectID, askSearchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ideas: %w", err)
+	}
+
+	answer := &model.Answer{Question: question}
+	if len(ideas) == 0 {
+		answer.Text = "Похожих идей в базе не найдено."
+		return answer, nil
+	}
+
+	answer.Text, err = s.claudeService.AnswerQuestion(ctx, question, ideas)
+	if err != nil {
+		return nil, err
+	}
+
+	retrieved := make(map[int64]*model.Idea, len(ideas))
+	for _, idea := range ideas {
+		retrieved[idea.ID] = idea
+	}
+
+	cited := make(map[int64]bool)
+	for _, m := range citationRe.FindAllStringSubmatch(answer.Text, -1) {
+		id, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || cited[id] {
+			continue
+		}
+		// Ignore citations of ideas that weren't given to the model
+		if idea, ok := retrieved[id]; ok {
+			cited[id] = true
+			answer.Sources = append(answer.Sources, idea)
+		}
+	}
+
+	return answer, nil
+}
+
+// ProjectForChat returns the project of a chat or forum topic, nil when it has none
+func (s *IdeaService) ProjectForChat(chatID, threadID int64) (*model.Project, error) {
+	return s.projects.ForChat(chatID, threadID)
+}
+
+// Assign makes a Telegram user responsible for an idea, an empty username unassigns it
+func (s *IdeaService) Assign(id int64, username string) error {
+	username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+	return s.repo.UpdateAssignee(id, username)
+}
+
+// validateUsername rejects assignees that can't be Telegram usernames
+func validateUsername(username string) error {
+	if strings.ContainsAny(username, " \t\n") {
+		return &ValidationError{Message: fmt.Sprintf("Неверное имя пользователя %q", username)}
+	}
+	return nil
+}
+
+// autoAssign assigns an unassigned idea to the owner of its affected components
+func (s *IdeaService) autoAssign(idea *model.Idea, project *model.Project, enriched *model.EnrichedIdea) {
+	if idea.Assignee != "" || project == nil {
+		return
+	}
+	owner := project.OwnerOf(enriched.AffectedComponents)
+	if owner == "" {
+		return
+	}
+	if err := s.repo.UpdateAssignee(idea.ID, owner); err != nil {
+		log.Printf("Warning: failed to assign idea %d to @%s: %v", idea.ID, owner, err)
+		return
+	}
+	log.Printf("Idea %d assigned to @%s", idea.ID, owner)
+}
+
+// UpdateScoring saves RICE factors entered by a human. The idea keeps them
+// over the ones proposed by later analyses until ResetScoring.
+func (s *IdeaService) UpdateScoring(id int64, scoring model.Scoring) error {
+	if err := validateScoring(scoring); err != nil {
+		return err
+	}
+	if scoring.Effort <= 0 {
+		return &ValidationError{Message: "Трудозатраты должны быть больше нуля"}
+	}
+
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	overridden := idea.OverriddenFields
+	if !idea.IsOverridden("scoring") {
+		overridden = append(overridden, "scoring")
+	}
+	return s.repo.UpdateScoring(id, scoring, overridden)
+}
+
+// ResetScoring replaces human-entered RICE factors with the ones proposed by the
+// current analysis, the idea is left unscored when the analysis has none
+func (s *IdeaService) ResetScoring(id int64) error {
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	overridden := make([]string, 0, len(idea.OverriddenFields))
+	for _, f := range idea.OverriddenFields {
+		if f != "scoring" {
+			overridden = append(overridden, f)
+		}
+	}
+
+	var scoring model.Scoring
+	if proposed, ok := proposedScoring(idea.Enriched); ok {
+		scoring = proposed
+	}
+	return s.repo.UpdateScoring(id, scoring, overridden)
+}
+
+// proposeScore scores an idea with the factors proposed by its analysis,
+// unless a human has scored it
+func (s *IdeaService) proposeScore(idea *model.Idea, enriched *model.EnrichedIdea) {
+	if idea.IsOverridden("scoring") {
+		return
+	}
+	scoring, ok := proposedScoring(enriched)
+	if !ok {
+		return
+	}
+	if err := s.repo.UpdateScoring(idea.ID, scoring, nil); err != nil {
+		log.Printf("Warning: failed to score idea %d: %v", idea.ID, err)
+	}
+}
+
+// proposedScoring returns the RICE factors proposed by an analysis with the effort
+// derived from its complexity; false when the analysis has no valid proposal
+func proposedScoring(enriched *model.EnrichedIdea) (model.Scoring, bool) {
+	if enriched == nil || enriched.Scoring == nil {
+		return model.Scoring{}, false
+	}
+	scoring := *enriched.Scoring
+	scoring.Effort = model.IdeaComplexity(enriched.Complexity).Effort()
+	if err := validateScoring(scoring); err != nil {
+		log.Printf("Warning: ignoring proposed scoring %s: %v", scoring, err)
+		return model.Scoring{}, false
+	}
+	return scoring, true
+}
+
+// validateScoring checks the ranges of RICE factors; effort may be 0 when unknown
+func validateScoring(scoring model.Scoring) error {
+	if scoring.Reach < 0 {
+		return &ValidationError{Message: "Охват не может быть отрицательным"}
+	}
+	if !model.IsImpactLevel(scoring.Impact) {
+		return &ValidationError{Message: fmt.Sprintf("Недопустимое влияние %g", scoring.Impact)}
+	}
+	if scoring.Confidence < 0 || scoring.Confidence > 100 {
+		return &ValidationError{Message: "Уверенность должна быть от 0 до 100%"}
+	}
+	if scoring.Effort < 0 {
+		return &ValidationError{Message: "Трудозатраты не могут быть отрицательными"}
+	}
+	return nil
+}
+
+// AddTag tags an idea, creating the tag when it doesn't exist yet
+func (s *IdeaService) AddTag(id int64, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	return s.tags.AddToIdeas([]int64{id}, name, model.DefaultTagColor(name))
+}
+
+// RemoveTag removes a tag from an idea
+func (s *IdeaService) RemoveTag(id int64, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	return s.tags.RemoveFromIdea(id, name)
+}
+
+// ToggleVote adds the user's vote for an idea or takes it back.
+// It reports whether the user votes for the idea now.
+func (s *IdeaService) ToggleVote(id, userID int64) (bool, error) {
+	return s.votes.Toggle(id, userID)
+}
+
+// tagNames returns the names of all tags, which the analysis may suggest
+func (s *IdeaService) tagNames() []string {
+	tags, err := s.tags.List()
+	if err != nil {
+		log.Printf("Warning: failed to list tags for enrichment: %v", err)
+		return nil
+	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// knownTags keeps the suggested tags that exist, the analysis may not invent new ones
+func knownTags(existing, suggested []string) []string {
+	known := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		known[name] = true
+	}
+	var result []string
+	for _, name := range suggested {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+		if known[name] {
+			result = append(result, name)
+			known[name] = false
+		}
+	}
+	return result
+}
+
+// projectOf returns the project of an idea, nil when it has none
+func (s *IdeaService) projectOf(idea *model.Idea) (*model.Project, error) {
+	if idea.ProjectID == 0 {
+		return nil, nil
+	}
+	project, err := s.projects.GetByID(idea.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project of idea %d: %w", idea.ID, err)
+	}
+	return project, nil
+}
+
+// normalizeComponents maps the affected components of an analysis to the project's
+// catalog entries and flags the ones the catalog doesn't know
+func normalizeComponents(project *model.Project, enriched *model.EnrichedIdea) {
+	if project == nil || len(project.Components) == 0 {
+		enriched.UnknownComponents = nil
+		return
+	}
+	enriched.AffectedComponents, enriched.UnknownComponents = model.NormalizeComponents(project.Components, enriched.AffectedComponents)
+}
+
+// limiterFor returns the rate limiter for new ideas of a project. Projects without
+// their own limits share the global limiter.
+func (s *IdeaService) limiterFor(project *model.Project) *RateLimiter {
+	if project == nil || (project.RateLimitPerUser == 0 && project.RateLimitGlobal == 0) {
+		return s.rateLimiter
+	}
+
+	cfg := config.Get()
+	perUser, global := project.RateLimitPerUser, project.RateLimitGlobal
+	if perUser == 0 {
+		perUser = cfg.RateLimit.PerUser
+	}
+	if global == 0 {
+		global = cfg.RateLimit.Global
+	}
+
+	s.limitersMu.Lock()
+	defer s.limitersMu.Unlock()
+
+	// Limits edited in the web UI take effect with a fresh limiter
+	pl, ok := s.projectLimiters[project.ID]
+	if !ok || pl.perUser != perUser || pl.global != global {
+		pl = &projectLimiter{perUser: perUser, global: global, limiter: NewRateLimiter(perUser, global)}
+		s.projectLimiters[project.ID] = pl
+	}
+	return pl.limiter
+}
+
 // RateLimiter handles rate limiting per user and globally
 type RateLimiter struct {
 	userLimits  map[int64]*rate.Limiter
@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+// initStorage opens an empty database for a test
+func initStorage(t *testing.T) {
+	t.Helper()
+	config.Load()
+	if err := storage.Init(t.TempDir() + "/ideas.db"); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+}
+
+func TestUpdateStatus(t *testing.T) {
+	initStorage(t)
+
+	repo := storage.NewIdeaRepository()
+	ideaService := service.NewIdeaService()
+
+	tests := []struct {
+		name      string
+		from      model.IdeaStatus
+		to        model.IdeaStatus
+		comment   string
+		wantValid bool
+	}{
+		{name: "allowed transition", from: model.StatusNew, to: model.StatusReviewed, wantValid: true},
+		{name: "same status", from: model.StatusAccepted, to: model.StatusAccepted, wantValid: true},
+		{name: "reject with a comment", from: model.StatusNew, to: model.StatusRejected, comment: "Уже есть", wantValid: true},
+		{name: "unknown status", from: model.StatusNew, to: "archived"},
+		{name: "disallowed transition", from: model.StatusNew, to: model.StatusImplemented},
+		{name: "leaving a terminal status", from: model.StatusRejected, to: model.StatusNew},
+		{name: "reject without a comment", from: model.StatusNew, to: model.StatusRejected},
+		{name: "reject with a blank comment", from: model.StatusNew, to: model.StatusRejected, comment: "  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idea, err := repo.Create(model.CreateIdeaInput{TelegramChatID: -100, TelegramUserID: 1, RawText: tt.name})
+			if err != nil {
+				t.Fatalf("create idea: %v", err)
+			}
+			if _, err := storage.DB().Exec(`UPDATE ideas SET status = ? WHERE id = ?`, string(tt.from), idea.ID); err != nil {
+				t.Fatalf("set status: %v", err)
+			}
+
+			err = ideaService.UpdateStatus(idea.ID, tt.to, tt.comment)
+
+			want := tt.to
+			if tt.wantValid {
+				if err != nil {
+					t.Fatalf("UpdateStatus(%s → %s): %v", tt.from, tt.to, err)
+				}
+			} else {
+				var validationErr *service.ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("UpdateStatus(%s → %s) = %v, want a ValidationError", tt.from, tt.to, err)
+				}
+				want = tt.from
+			}
+
+			got, err := repo.GetByID(idea.ID)
+			if err != nil {
+				t.Fatalf("get idea: %v", err)
+			}
+			if got.Status != want {
+				t.Errorf("status = %s, want %s", got.Status, want)
+			}
+		})
+	}
+}
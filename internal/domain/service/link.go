@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// maxChildLength is the longest text of an idea created from an epic breakdown
+const maxChildLength = 2000
+
+// Link links an idea to another one, e.g. RelationBlocks means the idea blocks otherID.
+// Two ideas can be linked once, and an idea can be a part of one epic only.
+// A non-zero projectID limits linking to ideas of that project.
+func (s *IdeaService) Link(id int64, relation model.IdeaRelation, otherID, projectID int64) error {
+	if !relation.IsValid() {
+		return &ValidationError{Message: fmt.Sprintf("Неизвестная связь %q", relation)}
+	}
+	if id == otherID {
+		return &ValidationError{Message: "Нельзя связать идею с самой собой"}
+	}
+
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if idea.MergedIntoID != 0 {
+		return &ValidationError{Message: fmt.Sprintf("Идея #%d уже объединена с #%d", id, idea.MergedIntoID)}
+	}
+	other, err := s.repo.GetByID(otherID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (other.MergedIntoID != 0 || (projectID != 0 && other.ProjectID != projectID))) {
+		return &ValidationError{Message: fmt.Sprintf("Идея #%d не найдена", otherID)}
+	}
+	if err != nil {
+		return err
+	}
+	if idea.ProjectID != other.ProjectID {
+		return &ValidationError{Message: fmt.Sprintf("Идеи #%d и #%d из разных проектов", id, otherID)}
+	}
+
+	linked, err := s.links.Linked(id, otherID)
+	if err != nil {
+		return err
+	}
+	if linked {
+		return &ValidationError{Message: fmt.Sprintf("Идеи #%d и #%d уже связаны", id, otherID)}
+	}
+
+	linkType, outgoing := relation.Link()
+	fromID, toID := id, otherID
+	if !outgoing {
+		fromID, toID = otherID, id
+	}
+	if linkType == model.LinkParent {
+		if err := s.checkParent(fromID, toID); err != nil {
+			return err
+		}
+	}
+
+	return s.links.Create(fromID, toID, linkType)
+}
+
+// Unlink removes a link of an idea
+func (s *IdeaService) Unlink(id, linkID int64) error {
+	return s.links.Delete(id, linkID)
+}
+
+// ListLinks returns the ideas linked to an idea
+func (s *IdeaService) ListLinks(id int64) ([]model.LinkedIdea, error) {
+	return s.links.ListByIdea(id)
+}
+
+// ProposeBreakdown asks the AI how an epic could be split into smaller ideas.
+// Nothing is stored: the proposal is shown for review and passed to CreateChildren.
+func (s *IdeaService) ProposeBreakdown(ctx context.Context, id int64) ([]model.BreakdownItem, error) {
+	idea, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.claudeService.BreakDownEpic(ctx, idea)
+}
+
+// CreateChildren creates ideas from the reviewed parts of an epic and links them to it.
+// The parts are posted in the epic's chat and project on behalf of its author;
+// they still need to be analyzed.
+func (s *IdeaService) CreateChildren(parentID int64, items []model.BreakdownItem) ([]*model.Idea, error) {
+	var texts []string
+	for _, item := range items {
+		title := strings.TrimSpace(item.Title)
+		description := strings.TrimSpace(item.Description)
+		if title == "" {
+			continue
+		}
+		text := title
+		if description != "" {
+			text += "\n\n" + description
+		}
+		if len([]rune(text)) > maxChildLength {
+			return nil, &ValidationError{Message: fmt.Sprintf("Текст части «%s» длиннее %d символов", title, maxChildLength)}
+		}
+		texts = append(texts, text)
+	}
+	if len(texts) == 0 {
+		return nil, &ValidationError{Message: "Не выбрано ни одной части"}
+	}
+
+	parent, err := s.repo.GetByID(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []*model.Idea
+	for _, text := range texts {
+		child, err := s.repo.Create(model.CreateIdeaInput{
+			TelegramMessageID: parent.TelegramMessageID,
+			TelegramChatID:    parent.TelegramChatID,
+			MessageChatID:     parent.MessageChatID,
+			TelegramUserID:    parent.TelegramUserID,
+			TelegramUsername:  parent.TelegramUsername,
+			TelegramFirstName: parent.TelegramFirstName,
+			RawText:           text,
+			Source:            model.SourceBreakdown,
+			MessageThreadID:   parent.MessageThreadID,
+			ProjectID:         parent.ProjectID,
+		})
+		if err != nil {
+			return children, fmt.Errorf("failed to create a part of idea %d: %w", parentID, err)
+		}
+		if err := s.links.Create(parentID, child.ID, model.LinkParent); err != nil {
+			return children, fmt.Errorf("failed to link idea %d to idea %d: %w", child.ID, parentID, err)
+		}
+		children = append(children, child)
+	}
+
+	return children, nil
+}
+
+// checkParent rejects making epicID the epic of childID when the child already has
+// an epic or when it would make a cycle
+func (s *IdeaService) checkParent(epicID, childID int64) error {
+	parentID, err := s.links.ParentOf(childID)
+	if err != nil {
+		return err
+	}
+	if parentID != 0 {
+		return &ValidationError{Message: fmt.Sprintf("Идея #%d уже часть эпика #%d", childID, parentID)}
+	}
+
+	for ancestor := epicID; ancestor != 0; {
+		if ancestor == childID {
+			return &ValidationError{Message: fmt.Sprintf("Идея #%d входит в идею #%d, связь замкнёт цикл", epicID, childID)}
+		}
+		if ancestor, err = s.links.ParentOf(ancestor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
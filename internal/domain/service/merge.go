@@ -0,0 +1,71 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// OnMerged registers a hook that is called after a duplicate has been merged into
+// another idea, with both ideas as they are after the merge
+func (s *IdeaService) OnMerged(hook func(idea, into *model.Idea)) {
+	s.onMerged = append(s.onMerged, hook)
+}
+
+// Merge marks idea id as a duplicate of idea intoID. The duplicate stops being listed,
+// its text is added to the merge history of the other idea and its attachments and
+// tags move there. A non-zero projectID limits the merge to ideas of that project.
+func (s *IdeaService) Merge(id, intoID, projectID int64) (*model.Idea, error) {
+	if id == intoID {
+		return nil, &ValidationError{Message: "Нельзя объединить идею с самой собой"}
+	}
+
+	idea, err := s.mergeTarget(id, projectID)
+	if err != nil {
+		return nil, err
+	}
+	into, err := s.mergeTarget(intoID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if idea.ProjectID != into.ProjectID {
+		return nil, &ValidationError{Message: fmt.Sprintf("Идеи #%d и #%d из разных проектов", id, intoID)}
+	}
+
+	if err := s.repo.Merge(id, intoID); err != nil {
+		return nil, err
+	}
+
+	if idea, err = s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	if into, err = s.repo.GetByID(intoID); err != nil {
+		return nil, err
+	}
+	for _, hook := range s.onMerged {
+		hook(idea, into)
+	}
+	return into, nil
+}
+
+// ListMerges returns the duplicates merged into an idea
+func (s *IdeaService) ListMerges(id int64) ([]model.IdeaMerge, error) {
+	return s.repo.ListMerges(id)
+}
+
+// mergeTarget loads an idea taking part in a merge, which must not be merged already
+func (s *IdeaService) mergeTarget(id, projectID int64) (*model.Idea, error) {
+	idea, err := s.repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && projectID != 0 && idea.ProjectID != projectID) {
+		return nil, &ValidationError{Message: fmt.Sprintf("Идея #%d не найдена", id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if idea.MergedIntoID != 0 {
+		return nil, &ValidationError{Message: fmt.Sprintf("Идея #%d уже объединена с #%d", id, idea.MergedIntoID)}
+	}
+	return idea, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginCacheTTL is how long a verified project login is trusted without checking the
+// bcrypt hash again; browsers send credentials with every request, images included
+const loginCacheTTL = 5 * time.Minute
+
+type projectLogin struct {
+	projectID int64
+	expires   time.Time
+}
+
+type ProjectService struct {
+	repo       *storage.ProjectRepository
+	components *storage.ComponentRepository
+
+	logins   map[[32]byte]projectLogin
+	loginsMu sync.Mutex
+}
+
+func NewProjectService() *ProjectService {
+	return &ProjectService{
+		repo:       storage.NewProjectRepository(),
+		components: storage.NewComponentRepository(),
+		logins:     make(map[[32]byte]projectLogin),
+	}
+}
+
+// List returns all projects
+func (s *ProjectService) List() ([]*model.Project, error) {
+	return s.repo.List()
+}
+
+// GetByID returns a project by ID
+func (s *ProjectService) GetByID(id int64) (*model.Project, error) {
+	return s.repo.GetByID(id)
+}
+
+// ForChat returns the project of a chat or forum topic, nil when it has none
+func (s *ProjectService) ForChat(chatID, threadID int64) (*model.Project, error) {
+	return s.repo.ForChat(chatID, threadID)
+}
+
+// Save validates and stores a project, creating it when p.ID is 0.
+// A non-empty password replaces the project's web password.
+func (s *ProjectService) Save(p *model.Project, password string) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.WebUsername = strings.TrimSpace(p.WebUsername)
+	if p.Name == "" {
+		return &ValidationError{Message: "Название проекта не может быть пустым"}
+	}
+	if p.RateLimitPerUser < 0 || p.RateLimitGlobal < 0 {
+		return &ValidationError{Message: "Лимиты не могут быть отрицательными"}
+	}
+
+	projects, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+	for _, other := range projects {
+		if other.ID == p.ID {
+			continue
+		}
+		if strings.EqualFold(other.Name, p.Name) {
+			return &ValidationError{Message: fmt.Sprintf("Проект %q уже существует", p.Name)}
+		}
+		if p.WebUsername != "" && other.WebUsername == p.WebUsername {
+			return &ValidationError{Message: fmt.Sprintf("Логин %q уже занят проектом %q", p.WebUsername, other.Name)}
+		}
+		for _, c := range p.Chats {
+			for _, oc := range other.Chats {
+				if c == oc {
+					return &ValidationError{Message: fmt.Sprintf("Чат %d уже привязан к проекту %q", c.ChatID, other.Name)}
+				}
+			}
+		}
+	}
+
+	if p.WebUsername != "" && p.WebUsername == config.Get().Web.Username {
+		return &ValidationError{Message: "Логин проекта совпадает с логином администратора"}
+	}
+
+	switch {
+	case p.WebUsername == "":
+		p.WebPasswordHash = ""
+	case password != "":
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		p.WebPasswordHash = string(hash)
+	case p.WebPasswordHash == "":
+		return &ValidationError{Message: "Задайте пароль для входа в веб-интерфейс"}
+	}
+
+	if p.ID == 0 {
+		p.ID, err = s.repo.Create(p)
+	} else {
+		err = s.repo.Update(p)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.forgetLogins()
+	return nil
+}
+
+// Delete removes a project, its ideas stay without a project
+func (s *ProjectService) Delete(id int64) error {
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.forgetLogins()
+	return nil
+}
+
+// SaveComponent validates and stores an entry of a project's component catalog,
+// creating it when c.ID is 0. Names and aliases must be unique within the project.
+func (s *ProjectService) SaveComponent(c *model.Component) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	c.Owner = strings.TrimPrefix(strings.TrimSpace(c.Owner), "@")
+	if c.Name == "" {
+		return &ValidationError{Message: "Название компонента не может быть пустым"}
+	}
+
+	catalog, err := s.components.ListByProject(c.ProjectID)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string)
+	for _, other := range catalog {
+		if other.ID == c.ID {
+			continue
+		}
+		for _, key := range other.Keys() {
+			taken[key] = other.Name
+		}
+	}
+	for i, key := range c.Keys() {
+		name := c.Name
+		if i > 0 {
+			name = c.Aliases[i-1]
+		}
+		if other, ok := taken[key]; ok {
+			return &ValidationError{Message: fmt.Sprintf("%q уже используется компонентом %q", name, other)}
+		}
+	}
+
+	if c.ID == 0 {
+		c.ID, err = s.components.Create(c)
+		return err
+	}
+	return s.components.Update(c)
+}
+
+// DeleteComponent removes an entry of a project's component catalog.
+// Ideas keep the component names they were analyzed with.
+func (s *ProjectService) DeleteComponent(projectID, id int64) error {
+	return s.components.Delete(projectID, id)
+}
+
+// Authenticate checks web credentials of a project member and returns their project
+func (s *ProjectService) Authenticate(username, password string) (*model.Project, bool) {
+	key := sha256.Sum256([]byte(username + "\x00" + password))
+
+	s.loginsMu.Lock()
+	login, ok := s.logins[key]
+	s.loginsMu.Unlock()
+	if ok && time.Now().Before(login.expires) {
+		project, err := s.repo.GetByID(login.projectID)
+		return project, err == nil
+	}
+
+	project, err := s.repo.GetByWebUsername(username)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error looking up project login %q: %v", username, err)
+		}
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(project.WebPasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+
+	s.loginsMu.Lock()
+	s.logins[key] = projectLogin{projectID: project.ID, expires: time.Now().Add(loginCacheTTL)}
+	s.loginsMu.Unlock()
+
+	return project, true
+}
+
+// forgetLogins drops cached logins so changed passwords and deleted projects apply at once
+func (s *ProjectService) forgetLogins() {
+	s.loginsMu.Lock()
+	s.logins = make(map[[32]byte]projectLogin)
+	s.loginsMu.Unlock()
+}
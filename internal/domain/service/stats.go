@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+const (
+	// dashboardWeeks is how many weeks the ideas-per-week chart covers
+	dashboardWeeks = 12
+	// topSubmittersLimit is how many submitters the dashboard lists
+	topSubmittersLimit = 10
+)
+
+type StatsService struct {
+	repo  *storage.StatsRepository
+	ideas *storage.IdeaRepository
+}
+
+func NewStatsService() *StatsService {
+	return &StatsService{
+		repo:  storage.NewStatsRepository(),
+		ideas: storage.NewIdeaRepository(),
+	}
+}
+
+// Dashboard collects the analytics of all ideas, or of a project's ideas when projectID is not 0
+func (s *StatsService) Dashboard(projectID int64) (*model.Dashboard, error) {
+	d := &model.Dashboard{}
+	var err error
+
+	if d.Total, err = s.ideas.Count(model.IdeaFilter{ProjectID: projectID}); err != nil {
+		return nil, err
+	}
+	if d.Weeks, err = s.weeks(time.Now(), projectID); err != nil {
+		return nil, err
+	}
+
+	workflow := model.CurrentWorkflow()
+	if d.ByStatus, err = buckets(s.repo, "status", workflow.Statuses(), projectID); err != nil {
+		return nil, err
+	}
+	if d.ByCategory, err = buckets(s.repo, "category", model.AllCategories(), projectID); err != nil {
+		return nil, err
+	}
+	if d.ByPriority, err = buckets(s.repo, "priority", model.AllPriorities(), projectID); err != nil {
+		return nil, err
+	}
+	if d.ByComplexity, err = buckets(s.repo, "complexity", model.AllComplexities(), projectID); err != nil {
+		return nil, err
+	}
+
+	if d.TimeToDecision, err = s.timeTo(decisionStatuses(workflow), projectID); err != nil {
+		return nil, err
+	}
+	if d.TimeToImplementation, err = s.timeTo([]model.IdeaStatus{model.StatusImplemented}, projectID); err != nil {
+		return nil, err
+	}
+
+	if d.TopSubmitters, err = s.repo.TopSubmitters(topSubmittersLimit, projectID); err != nil {
+		return nil, err
+	}
+	if d.Groups, err = s.repo.CountByChat(workflow.OpenStatuses(), model.StatusImplemented, projectID); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// weeks returns the number of ideas in each of the last dashboardWeeks weeks, oldest first
+func (s *StatsService) weeks(now time.Time, projectID int64) ([]model.WeekCount, error) {
+	now = now.UTC()
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday = monday.AddDate(0, 0, -(int(monday.Weekday())+6)%7)
+	first := monday.AddDate(0, 0, -7*(dashboardWeeks-1))
+
+	counts, err := s.repo.CountByWeek(first, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ideas by week: %w", err)
+	}
+
+	weeks := make([]model.WeekCount, dashboardWeeks)
+	for i := range weeks {
+		week := first.AddDate(0, 0, 7*i)
+		weeks[i] = model.WeekCount{Week: week, Count: counts[week.Format("2006-01-02")]}
+	}
+	return weeks, nil
+}
+
+// labeled is an enum with human-readable values
+type labeled interface {
+	~string
+	Label() string
+}
+
+// buckets counts ideas by a field in the order of its known values. Ideas without
+// a value, e.g. not analyzed yet, and values no longer known are counted last.
+func buckets[T labeled](repo *storage.StatsRepository, field string, values []T, projectID int64) ([]model.StatBucket, error) {
+	counts, err := repo.CountByField(field, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ideas by %s: %w", field, err)
+	}
+
+	result := make([]model.StatBucket, 0, len(values)+1)
+	for _, v := range values {
+		result = append(result, model.StatBucket{Label: v.Label(), Count: counts[string(v)]})
+		delete(counts, string(v))
+	}
+
+	other := 0
+	for _, count := range counts {
+		other += count
+	}
+	if other > 0 {
+		result = append(result, model.StatBucket{Label: "Не указано", Count: other})
+	}
+	return result, nil
+}
+
+// timeTo returns the median time from submission to the first move into one of the statuses
+func (s *StatsService) timeTo(statuses []model.IdeaStatus, projectID int64) (model.DurationStat, error) {
+	durations, err := s.repo.TimesToStatus(statuses, projectID)
+	if err != nil {
+		return model.DurationStat{}, err
+	}
+	return model.DurationStat{Median: median(durations), Count: len(durations)}, nil
+}
+
+// decisionStatuses are the statuses that mean a decision was made about an idea:
+// accepted and every final status other than implemented, such as rejected
+func decisionStatuses(w *model.Workflow) []model.IdeaStatus {
+	var statuses []model.IdeaStatus
+	for _, state := range w.States {
+		if state.Status == model.StatusAccepted || (state.Terminal && state.Status != model.StatusImplemented) {
+			statuses = append(statuses, state.Status)
+		}
+	}
+	return statuses
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mid := len(durations) / 2
+	if len(durations)%2 == 1 {
+		return durations[mid]
+	}
+	return (durations[mid-1] + durations[mid]) / 2
+}
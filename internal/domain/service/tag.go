@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+// maxTagLength is the longest tag name in characters
+const maxTagLength = 32
+
+type TagService struct {
+	repo *storage.TagRepository
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		repo: storage.NewTagRepository(),
+	}
+}
+
+// List returns all tags with their idea counts
+func (s *TagService) List() ([]*model.Tag, error) {
+	return s.repo.List()
+}
+
+// Save validates and stores a tag, creating it when t.ID is 0.
+// An empty color picks one from the palette.
+func (s *TagService) Save(t *model.Tag) error {
+	name, err := normalizeTagName(t.Name)
+	if err != nil {
+		return err
+	}
+	t.Name = name
+
+	t.Color = strings.TrimSpace(t.Color)
+	if t.Color == "" {
+		t.Color = model.DefaultTagColor(t.Name)
+	}
+	if !model.IsTagColor(t.Color) {
+		return &ValidationError{Message: fmt.Sprintf("Неверный цвет %q, нужен формат #rrggbb", t.Color)}
+	}
+
+	tags, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+	for _, other := range tags {
+		if other.ID != t.ID && other.Name == t.Name {
+			return &ValidationError{Message: fmt.Sprintf("Тег %q уже существует", t.Name)}
+		}
+	}
+
+	if t.ID == 0 {
+		t.ID, err = s.repo.Create(t)
+		return err
+	}
+	return s.repo.Update(t)
+}
+
+// Delete removes a tag from all ideas
+func (s *TagService) Delete(id int64) error {
+	return s.repo.Delete(id)
+}
+
+// normalizeTagName lower-cases a tag name and drops a leading #. Names are single words,
+// so they can be typed in Telegram commands and listed with commas.
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", &ValidationError{Message: "Название тега не может быть пустым"}
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		return "", &ValidationError{Message: fmt.Sprintf("Тег %q не может содержать пробелы и запятые", name)}
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", &ValidationError{Message: fmt.Sprintf("Тег длиннее %d символов", maxTagLength)}
+	}
+	return name, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+// themeBatchSize limits how many ideas are sent to Claude in one clustering request
+const themeBatchSize = 40
+
+type ThemeService struct {
+	repo          *storage.ThemeRepository
+	claudeService *ClaudeService
+	interval      time.Duration
+	trigger       chan struct{}
+}
+
+func NewThemeService() *ThemeService {
+	cfg := config.Get()
+	return &ThemeService{
+		repo:          storage.NewThemeRepository(),
+		claudeService: NewClaudeService(),
+		interval:      cfg.Themes.Interval,
+		trigger:       make(chan struct{}, 1),
+	}
+}
+
+// List returns all themes with their member ideas
+func (s *ThemeService) List() ([]*model.Theme, error) {
+	return s.repo.ListWithIdeas()
+}
+
+// CountUnclustered returns the number of open ideas waiting for a theme
+func (s *ThemeService) CountUnclustered() (int, error) {
+	return s.repo.CountUnclustered()
+}
+
+// Notify asks the background job to cluster new ideas without waiting for the next tick
+func (s *ThemeService) Notify() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+		// A run is already pending
+	}
+}
+
+// Run clusters new ideas periodically and whenever Notify is called, until ctx is cancelled
+func (s *ThemeService) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		log.Println("Theme clustering disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ClusterPending(ctx); err != nil {
+			log.Printf("Theme clustering error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+	}
+}
+
+// ClusterPending assigns all open ideas without a theme, in batches.
+// Every idea is sent once, including the ones Claude leaves without a theme,
+// so each run only costs as much as the new ideas.
+func (s *ThemeService) ClusterPending(ctx context.Context) error {
+	for {
+		ideas, err := s.repo.ListUnclustered(themeBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list unclustered ideas: %w", err)
+		}
+		if len(ideas) == 0 {
+			return nil
+		}
+
+		assigned, err := s.clusterBatch(ctx, ideas)
+		if err != nil {
+			return err
+		}
+		log.Printf("Clustered %d of %d ideas into themes", assigned, len(ideas))
+
+		ids := make([]int64, len(ideas))
+		for i, idea := range ideas {
+			ids[i] = idea.ID
+		}
+		if err := s.repo.MarkClustered(ids); err != nil {
+			return fmt.Errorf("failed to mark clustered ideas: %w", err)
+		}
+
+		if len(ideas) < themeBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *ThemeService) clusterBatch(ctx context.Context, ideas []model.IdeaSummary) (int, error) {
+	themes, err := s.repo.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list themes: %w", err)
+	}
+
+	clusterCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	result, err := s.claudeService.ClusterIdeas(clusterCtx, themes, ideas)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cluster ideas: %w", err)
+	}
+
+	themeIDs := make(map[string]int64, len(themes))
+	for _, t := range themes {
+		themeIDs[strings.ToLower(t.Name)] = t.ID
+	}
+
+	for _, nt := range result.NewThemes {
+		name := strings.TrimSpace(nt.Name)
+		if name == "" {
+			continue
+		}
+		if _, exists := themeIDs[strings.ToLower(name)]; exists {
+			continue
+		}
+		id, err := s.repo.Create(name, strings.TrimSpace(nt.Description))
+		if err != nil {
+			log.Printf("Warning: failed to create theme %q: %v", name, err)
+			continue
+		}
+		themeIDs[strings.ToLower(name)] = id
+	}
+
+	batch := make(map[int64]bool, len(ideas))
+	for _, idea := range ideas {
+		batch[idea.ID] = true
+	}
+
+	assigned := 0
+	for _, a := range result.Assignments {
+		// Ignore assignments for ideas that weren't in the batch
+		if !batch[a.IdeaID] {
+			continue
+		}
+		themeID, ok := themeIDs[strings.ToLower(strings.TrimSpace(a.Theme))]
+		if !ok {
+			log.Printf("Warning: idea %d assigned to unknown theme %q", a.IdeaID, a.Theme)
+			continue
+		}
+		if err := s.repo.AssignIdea(a.IdeaID, themeID); err != nil {
+			log.Printf("Warning: failed to assign idea %d to theme %d: %v", a.IdeaID, themeID, err)
+			continue
+		}
+		assigned++
+	}
+
+	return assigned, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// LoadWorkflow reads the status workflow from WORKFLOW_FILE and makes it active.
+// Without the file the default workflow stays active.
+func LoadWorkflow() error {
+	path := config.Get().Workflow.File
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read workflow: %w", err)
+	}
+
+	var workflow model.Workflow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&workflow); err != nil {
+		return fmt.Errorf("failed to parse workflow %s: %w", path, err)
+	}
+	if err := workflow.Validate(); err != nil {
+		return fmt.Errorf("invalid workflow %s: %w", path, err)
+	}
+
+	model.SetWorkflow(&workflow)
+	log.Printf("Loaded status workflow from %s (%d statuses)", path, len(workflow.States))
+	return nil
+}
@@ -0,0 +1,209 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+)
+
+// Mailer sends multipart (plaintext + HTML) emails over SMTP
+type Mailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func NewMailer() *Mailer {
+	cfg := config.Get()
+	return &Mailer{
+		host:     cfg.SMTP.Host,
+		port:     cfg.SMTP.Port,
+		username: cfg.SMTP.Username,
+		password: cfg.SMTP.Password,
+		from:     cfg.SMTP.From,
+	}
+}
+
+// Enabled reports whether SMTP is configured
+func (m *Mailer) Enabled() bool {
+	return m.host != "" && m.from != ""
+}
+
+// Send sends an email with plaintext and HTML alternatives to the recipients.
+// Cancelling ctx aborts the SMTP session.
+func (m *Mailer) Send(ctx context.Context, to []string, subject, textBody, htmlBody string) error {
+	if !m.Enabled() {
+		return fmt.Errorf("smtp is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	msg, err := m.buildMessage(to, subject, textBody, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	// SMTP_FROM may contain a display name, the envelope needs the bare address
+	sender := m.from
+	if parsed, err := mail.ParseAddress(m.from); err == nil {
+		sender = parsed.Address
+	}
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// smtp.Client has no context support: the connection is closed when ctx is done,
+	// which fails the command in progress
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := m.send(conn, auth, sender, to, msg); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// dial connects to the SMTP server. Port 465 uses implicit TLS, other ports
+// upgrade with STARTTLS when the server offers it.
+func (m *Mailer) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	if m.port == 465 {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: m.host}}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("tls dial: %w", err)
+		}
+		return conn, nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	return conn, nil
+}
+
+// send runs the SMTP session the way smtp.SendMail does, over an open connection
+func (m *Mailer) send(conn net.Conn, auth smtp.Auth, sender string, to []string, msg []byte) error {
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return fmt.Errorf("smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(sender); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *Mailer) buildMessage(to []string, subject, textBody, htmlBody string) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	from := m.from
+	if parsed, err := mail.ParseAddress(m.from); err == nil {
+		from = parsed.String()
+	}
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
+	buf.WriteString("\r\n")
+
+	writePart(&buf, boundary, "text/plain; charset=utf-8", textBody)
+	writePart(&buf, boundary, "text/html; charset=utf-8", htmlBody)
+
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+func writePart(buf *bytes.Buffer, boundary, contentType, body string) {
+	buf.WriteString("--" + boundary + "\r\n")
+	buf.WriteString("Content-Type: " + contentType + "\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	// Wrap base64 at 76 characters per RFC 2045
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "idea-bot-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,317 @@
+package mailer_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/mailer"
+	"github.com/josinSbazin/idea-bot/internal/storage"
+)
+
+// smtpSession is what the fake server received over one connection
+type smtpSession struct {
+	commands []string
+	data     string
+}
+
+// startFakeSMTP starts an SMTP server on a local port that accepts everything
+// and reports every finished session
+func startFakeSMTP(t *testing.T) (string, int, <-chan smtpSession) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			sessions <- serveSMTP(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, sessions
+}
+
+func serveSMTP(conn net.Conn) smtpSession {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var session smtpSession
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return session
+		}
+		session.commands = append(session.commands, line)
+
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch verb {
+		case "EHLO":
+			tp.PrintfLine("250-fake")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			tp.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL", "RCPT":
+			tp.PrintfLine("250 2.1.0 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return session
+			}
+			session.data = string(data)
+			tp.PrintfLine("250 2.0.0 Queued")
+		case "QUIT":
+			tp.PrintfLine("221 2.0.0 Bye")
+			return session
+		default:
+			tp.PrintfLine("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// configureSMTP points the configuration at the fake server
+func configureSMTP(t *testing.T, host string, port int) {
+	t.Helper()
+	config.Load()
+	cfg := config.Get()
+	cfg.SMTP.Host = host
+	cfg.SMTP.Port = port
+	cfg.SMTP.Username = "bot"
+	cfg.SMTP.Password = "secret"
+	cfg.SMTP.From = "Idea Bot <digest@example.com>"
+}
+
+// readSession waits for the fake server to finish a session
+func readSession(t *testing.T, sessions <-chan smtpSession) smtpSession {
+	t.Helper()
+	select {
+	case s := <-sessions:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fake SMTP server got no session")
+		return smtpSession{}
+	}
+}
+
+// parseMessage returns the decoded subject and the plaintext and HTML parts of a message
+func parseMessage(t *testing.T, data string) (string, string, string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		if enc := part.Header.Get("Content-Transfer-Encoding"); enc != "base64" {
+			t.Fatalf("part encoding = %q, want base64", enc)
+		}
+		body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		if err != nil {
+			t.Fatalf("decode part: %v", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(body)
+	}
+
+	return subject, parts["text/plain"], parts["text/html"]
+}
+
+func TestSend(t *testing.T) {
+	host, port, sessions := startFakeSMTP(t)
+	configureSMTP(t, host, port)
+
+	to := []string{"alice@example.com", "bob@example.com"}
+	err := mailer.NewMailer().Send(t.Context(), to, "Дайджест идей", "Привет, мир", "<p>Привет, мир</p>")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	session := readSession(t, sessions)
+	auth := base64.StdEncoding.EncodeToString([]byte("\x00bot\x00secret"))
+	want := []string{
+		"EHLO localhost",
+		"AUTH PLAIN " + auth,
+		"MAIL FROM:<digest@example.com>",
+		"RCPT TO:<alice@example.com>",
+		"RCPT TO:<bob@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	if strings.Join(session.commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(session.commands, "\n"), strings.Join(want, "\n"))
+	}
+
+	subject, text, html := parseMessage(t, session.data)
+	if subject != "Дайджест идей" {
+		t.Errorf("subject = %q", subject)
+	}
+	if text != "Привет, мир" {
+		t.Errorf("text part = %q", text)
+	}
+	if html != "<p>Привет, мир</p>" {
+		t.Errorf("html part = %q", html)
+	}
+}
+
+func TestSendCancelled(t *testing.T) {
+	// A server that accepts connections and never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conns <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		select {
+		case conn := <-conns:
+			conn.Close()
+		default:
+		}
+	})
+	addr := ln.Addr().(*net.TCPAddr)
+	configureSMTP(t, addr.IP.String(), addr.Port)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = mailer.NewMailer().Send(ctx, []string{"alice@example.com"}, "Дайджест идей", "Привет", "<p>Привет</p>")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send returned after %s", elapsed)
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	host, port, sessions := startFakeSMTP(t)
+	configureSMTP(t, host, port)
+	cfg := config.Get()
+	cfg.Web.BaseURL = "https://ideas.example.com"
+	cfg.Digest.EmailRecipients = []string{"team@example.com"}
+
+	if err := storage.Init(t.TempDir() + "/ideas.db"); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	repo := storage.NewIdeaRepository()
+	votes := storage.NewVoteRepository()
+	var ideas []*model.Idea
+	for _, text := range []string{"Тёмная тема", "Экспорт в PDF"} {
+		idea, err := repo.Create(model.CreateIdeaInput{TelegramChatID: -100, TelegramUserID: 1, RawText: text})
+		if err != nil {
+			t.Fatalf("create idea: %v", err)
+		}
+		ideas = append(ideas, idea)
+	}
+	for _, userID := range []int64{1, 2} {
+		if _, err := votes.Toggle(ideas[1].ID, userID); err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+	}
+	// The digest covers a period that ends now, so everything happened a bit earlier
+	hourAgo := time.Now().Add(-time.Hour).UTC().Format("2006-01-02 15:04:05")
+	if _, err := storage.DB().Exec(`UPDATE ideas SET created_at = ?, updated_at = ?`, hourAgo, hourAgo); err != nil {
+		t.Fatalf("backdate ideas: %v", err)
+	}
+	if _, err := storage.DB().Exec(`UPDATE idea_votes SET created_at = ?`, hourAgo); err != nil {
+		t.Fatalf("backdate votes: %v", err)
+	}
+
+	digestService, err := service.NewDigestService()
+	if err != nil {
+		t.Fatalf("NewDigestService: %v", err)
+	}
+	if err := digestService.SendEmail(t.Context()); err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	session := readSession(t, sessions)
+	if !containsLine(session.commands, "RCPT TO:<team@example.com>") {
+		t.Errorf("no RCPT for the recipient in %q", session.commands)
+	}
+
+	subject, text, html := parseMessage(t, session.data)
+	if !strings.HasPrefix(subject, "Дайджест идей за ") {
+		t.Errorf("subject = %q", subject)
+	}
+	secondID := strconv.FormatInt(ideas[1].ID, 10)
+	for _, want := range []string{
+		"НОВЫЕ ИДЕИ (2)",
+		"#" + strconv.FormatInt(ideas[0].ID, 10) + " Тёмная тема",
+		"БОЛЬШЕ ВСЕГО ГОЛОСОВ",
+		"#" + secondID + " Экспорт в PDF (+2, Новая)",
+		"https://ideas.example.com/ideas/" + secondID,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part has no %q:\n%s", want, text)
+		}
+	}
+	for _, want := range []string{
+		"Больше всего голосов",
+		`href="https://ideas.example.com/ideas/` + secondID + `"`,
+		"👍 2",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html part has no %q", want)
+		}
+	}
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is a cron-like weekly schedule: a set of weekdays, a time of day and a timezone
+type Schedule struct {
+	Weekdays [7]bool
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// Parse parses a schedule spec like "mon 09:00", "mon,thu 18:30 Europe/Moscow" or "daily 10:00 UTC".
+// When no timezone is given, the local timezone is used.
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("invalid schedule %q: expected \"<days> <HH:MM> [timezone]\"", spec)
+	}
+
+	s := &Schedule{Location: time.Local}
+
+	days := strings.ToLower(fields[0])
+	if days == "daily" || days == "*" {
+		for i := range s.Weekdays {
+			s.Weekdays[i] = true
+		}
+	} else {
+		for _, d := range strings.Split(days, ",") {
+			wd, ok := weekdayNames[strings.TrimSpace(d)]
+			if !ok {
+				return nil, fmt.Errorf("invalid schedule %q: unknown weekday %q", spec, d)
+			}
+			s.Weekdays[wd] = true
+		}
+	}
+
+	hm := strings.SplitN(fields[1], ":", 2)
+	if len(hm) != 2 {
+		return nil, fmt.Errorf("invalid schedule %q: time must be HH:MM", spec)
+	}
+	hour, err := strconv.Atoi(hm[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return nil, fmt.Errorf("invalid schedule %q: bad hour", spec)
+	}
+	minute, err := strconv.Atoi(hm[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return nil, fmt.Errorf("invalid schedule %q: bad minute", spec)
+	}
+	s.Hour, s.Minute = hour, minute
+
+	if len(fields) == 3 {
+		loc, err := time.LoadLocation(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		s.Location = loc
+	}
+
+	return s, nil
+}
+
+// Next returns the first scheduled time strictly after the given time
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.In(s.Location)
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, i)
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+		if s.Weekdays[candidate.Weekday()] && candidate.After(after) {
+			return candidate
+		}
+	}
+	// Unreachable for a valid schedule with at least one weekday
+	return after.Add(7 * 24 * time.Hour)
+}
+
+// String returns the schedule in its spec form
+func (s *Schedule) String() string {
+	var days []string
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if s.Weekdays[wd] {
+			days = append(days, strings.ToLower(wd.String()[:3]))
+		}
+	}
+	daysStr := strings.Join(days, ",")
+	if len(days) == 7 {
+		daysStr = "daily"
+	}
+	return fmt.Sprintf("%s %02d:%02d %s", daysStr, s.Hour, s.Minute, s.Location)
+}
+
+// Run calls fn at every scheduled time until ctx is cancelled
+func Run(ctx context.Context, name string, s *Schedule, fn func(ctx context.Context)) {
+	for {
+		next := s.Next(time.Now())
+		log.Printf("Scheduler %q: next run at %s", name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			log.Printf("Scheduler %q: running", name)
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "mon 09:00 UTC", want: "mon 09:00 UTC"},
+		{spec: "MON,thu 18:30 UTC", want: "mon,thu 18:30 UTC"},
+		{spec: "daily 07:05 UTC", want: "daily 07:05 UTC"},
+		{spec: "* 00:00 UTC", want: "daily 00:00 UTC"},
+		{spec: "mon", wantErr: true},
+		{spec: "mon 09:00 UTC extra", wantErr: true},
+		{spec: "someday 09:00", wantErr: true},
+		{spec: "mon 9", wantErr: true},
+		{spec: "mon 24:00", wantErr: true},
+		{spec: "mon 09:60", wantErr: true},
+		{spec: "mon 09:00 Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %s, want an error", tt.spec, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2026-03-04 is a Wednesday
+	utc := time.UTC
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name  string
+		spec  string
+		loc   *time.Location
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "later the same day",
+			spec:  "wed 09:00",
+			loc:   utc,
+			after: time.Date(2026, 3, 4, 8, 59, 0, 0, utc),
+			want:  time.Date(2026, 3, 4, 9, 0, 0, 0, utc),
+		},
+		{
+			name:  "exactly at the scheduled time moves to the next week",
+			spec:  "wed 09:00",
+			loc:   utc,
+			after: time.Date(2026, 3, 4, 9, 0, 0, 0, utc),
+			want:  time.Date(2026, 3, 11, 9, 0, 0, 0, utc),
+		},
+		{
+			name:  "next weekday of several",
+			spec:  "mon,fri 10:30",
+			loc:   utc,
+			after: time.Date(2026, 3, 4, 12, 0, 0, 0, utc),
+			want:  time.Date(2026, 3, 6, 10, 30, 0, 0, utc),
+		},
+		{
+			name:  "wraps over the end of the week",
+			spec:  "mon 09:00",
+			loc:   utc,
+			after: time.Date(2026, 3, 7, 23, 0, 0, 0, utc),
+			want:  time.Date(2026, 3, 9, 9, 0, 0, 0, utc),
+		},
+		{
+			name:  "daily after the time of day",
+			spec:  "daily 06:00",
+			loc:   utc,
+			after: time.Date(2026, 3, 31, 7, 0, 0, 0, utc),
+			want:  time.Date(2026, 4, 1, 6, 0, 0, 0, utc),
+		},
+		{
+			name:  "weekday is taken in the schedule's timezone",
+			spec:  "thu 01:00",
+			loc:   moscow,
+			after: time.Date(2026, 3, 4, 21, 30, 0, 0, utc), // Thursday 00:30 in Moscow
+			want:  time.Date(2026, 3, 5, 1, 0, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			s.Location = tt.loc
+
+			got := s.Next(tt.after)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+			if got.Location() != tt.loc {
+				t.Errorf("Next(%s) is in %s, want %s", tt.after, got.Location(), tt.loc)
+			}
+		})
+	}
+}
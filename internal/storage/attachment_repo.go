@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type AttachmentRepository struct {
+	db    *sql.DB
+	files FileStore
+}
+
+func NewAttachmentRepository() *AttachmentRepository {
+	return &AttachmentRepository{db: DB(), files: Files()}
+}
+
+// Enabled reports whether a file store is configured
+func (r *AttachmentRepository) Enabled() bool {
+	return r.files != nil
+}
+
+// Create stores the file contents and links the attachment to an idea
+func (r *AttachmentRepository) Create(ctx context.Context, ideaID int64, input model.AttachmentInput) (*model.Attachment, error) {
+	if r.files == nil {
+		return nil, fmt.Errorf("attachments storage is not configured")
+	}
+
+	key, err := attachmentKey(ideaID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.files.Put(ctx, key, input.Data, input.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	query := `
+		INSERT INTO attachments (idea_id, kind, file_name, content_type, size, storage_key, telegram_file_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.Exec(query, ideaID, string(input.Kind), input.FileName, input.ContentType,
+		len(input.Data), key, input.TelegramFileID, timestamp(time.Now()))
+	if err != nil {
+		if delErr := r.files.Delete(ctx, key); delErr != nil {
+			log.Printf("Warning: failed to remove orphaned file %s: %v", key, delErr)
+		}
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(id)
+}
+
+const attachmentColumns = `id, idea_id, kind, file_name, content_type, size, storage_key, telegram_file_id, created_at`
+
+// GetByID retrieves an attachment by ID
+func (r *AttachmentRepository) GetByID(id int64) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = ?`
+	return scanAttachment(r.db.QueryRow(query, id))
+}
+
+// ListByIdea returns the attachments of an idea in upload order
+func (r *AttachmentRepository) ListByIdea(ideaID int64) ([]*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE idea_id = ? ORDER BY id`
+
+	rows, err := r.db.Query(query, ideaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*model.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Open returns the contents of an attachment
+func (r *AttachmentRepository) Open(ctx context.Context, a *model.Attachment) (io.ReadCloser, error) {
+	if r.files == nil {
+		return nil, fmt.Errorf("attachments storage is not configured")
+	}
+	return r.files.Open(ctx, a.StorageKey)
+}
+
+// RemoveFiles removes the stored files of attachments. The rows are removed together
+// with the idea by IdeaRepository.BulkDelete, so the attachments are listed before.
+func (r *AttachmentRepository) RemoveFiles(ctx context.Context, attachments []*model.Attachment) error {
+	if r.files == nil {
+		return nil
+	}
+
+	for _, a := range attachments {
+		if err := r.files.Delete(ctx, a.StorageKey); err != nil {
+			return fmt.Errorf("failed to delete file %s: %w", a.StorageKey, err)
+		}
+	}
+	return nil
+}
+
+func scanAttachment(row rowScanner) (*model.Attachment, error) {
+	a := &model.Attachment{}
+	err := row.Scan(&a.ID, &a.IdeaID, &a.Kind, &a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.TelegramFileID, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+var safeExtRe = regexp.MustCompile(`^\.[A-Za-z0-9]{1,10}$`)
+
+// attachmentKey builds a unique storage key like "ideas/42/1f3a9c0e7b2d4a61.png"
+func attachmentKey(ideaID int64, input model.AttachmentInput) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(input.FileName)
+	if !safeExtRe.MatchString(ext) {
+		ext = ""
+	}
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(input.ContentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	return fmt.Sprintf("ideas/%d/%s%s", ideaID, hex.EncodeToString(buf), ext), nil
+}
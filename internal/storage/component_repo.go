@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type ComponentRepository struct {
+	db *sql.DB
+}
+
+func NewComponentRepository() *ComponentRepository {
+	return &ComponentRepository{db: DB()}
+}
+
+const componentColumns = `id, project_id, name, aliases, description, owner, created_at`
+
+// Create adds a component to a project's catalog
+func (r *ComponentRepository) Create(c *model.Component) (int64, error) {
+	aliases, err := json.Marshal(c.Aliases)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO components (project_id, name, aliases, description, owner, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, c.ProjectID, c.Name, string(aliases), c.Description, c.Owner, timestamp(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Update saves the name, aliases, description and owner of a component
+func (r *ComponentRepository) Update(c *model.Component) error {
+	aliases, err := json.Marshal(c.Aliases)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE components SET name = ?, aliases = ?, description = ?, owner = ?
+		WHERE id = ? AND project_id = ?
+	`, c.Name, string(aliases), c.Description, c.Owner, c.ID, c.ProjectID)
+	return err
+}
+
+// Delete removes a component from a project's catalog
+func (r *ComponentRepository) Delete(projectID, id int64) error {
+	_, err := r.db.Exec(`DELETE FROM components WHERE id = ? AND project_id = ?`, id, projectID)
+	return err
+}
+
+// ListByProject returns the catalog of a project ordered by name
+func (r *ComponentRepository) ListByProject(projectID int64) ([]model.Component, error) {
+	return listComponents(r.db, projectID)
+}
+
+func listComponents(db *sql.DB, projectID int64) ([]model.Component, error) {
+	rows, err := db.Query(`SELECT `+componentColumns+` FROM components WHERE project_id = ? ORDER BY name COLLATE NOCASE`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []model.Component
+	for rows.Next() {
+		var c model.Component
+		var aliases string
+		if err := rows.Scan(&c.ID, &c.ProjectID, &c.Name, &aliases, &c.Description, &c.Owner, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if aliases != "" {
+			_ = json.Unmarshal([]byte(aliases), &c.Aliases)
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// ApplyEnrichment saves a new enrichment version and makes it the active analysis of the idea
+func (r *IdeaRepository) ApplyEnrichment(id int64, enriched *model.EnrichedIdea, modelName, promptHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	version, err := insertEnrichmentVersion(tx, id, enriched, modelName, promptHash)
+	if err != nil {
+		return err
+	}
+
+	if err := updateEnriched(tx, id, enriched, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ApplyManualEdit saves human-edited enriched data as a new version and records
+// which fields are overridden, so re-enrichment keeps them
+func (r *IdeaRepository) ApplyManualEdit(id int64, enriched *model.EnrichedIdea, overridden []string) error {
+	overriddenJSON, err := json.Marshal(overridden)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	version, err := insertEnrichmentVersion(tx, id, enriched, model.ManualEditModel, "")
+	if err != nil {
+		return err
+	}
+
+	if err := updateEnriched(tx, id, enriched, version); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE ideas SET overridden_fields = ? WHERE id = ?`, string(overriddenJSON), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClearOverrides forgets manual overrides, the next re-enrichment replaces every field
+func (r *IdeaRepository) ClearOverrides(id int64) error {
+	_, err := r.db.Exec(`UPDATE ideas SET overridden_fields = '', updated_at = ? WHERE id = ?`, timestamp(time.Now()), id)
+	return err
+}
+
+// ActivateEnrichmentVersion makes a stored version the active analysis of the idea (rollback).
+// Manual edits of the analysis are forgotten along with it; a RICE score set by hand
+// is not part of any version and stays overridden.
+func (r *IdeaRepository) ActivateEnrichmentVersion(id int64, version int) error {
+	v, err := r.GetEnrichmentVersion(id, version)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateEnriched(tx, id, v.Enriched, version); err != nil {
+		return err
+	}
+
+	var overriddenStr string
+	if err := tx.QueryRow(`SELECT overridden_fields FROM ideas WHERE id = ?`, id).Scan(&overriddenStr); err != nil {
+		return err
+	}
+	var overridden []string
+	if overriddenStr != "" {
+		_ = json.Unmarshal([]byte(overriddenStr), &overridden)
+	}
+	kept := ""
+	if slices.Contains(overridden, "scoring") {
+		kept = `["scoring"]`
+	}
+	if _, err := tx.Exec(`UPDATE ideas SET overridden_fields = ? WHERE id = ?`, kept, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListEnrichmentVersions returns all enrichment versions of an idea, newest first
+func (r *IdeaRepository) ListEnrichmentVersions(id int64) ([]*model.EnrichmentVersion, error) {
+	query := `
+		SELECT id, idea_id, version, enriched_json, model, prompt_hash, created_at
+		FROM enrichment_versions WHERE idea_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*model.EnrichmentVersion
+	for rows.Next() {
+		v, err := scanEnrichmentVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetEnrichmentVersion returns a single enrichment version of an idea
+func (r *IdeaRepository) GetEnrichmentVersion(id int64, version int) (*model.EnrichmentVersion, error) {
+	query := `
+		SELECT id, idea_id, version, enriched_json, model, prompt_hash, created_at
+		FROM enrichment_versions WHERE idea_id = ? AND version = ?
+	`
+	return scanEnrichmentVersion(r.db.QueryRow(query, id, version))
+}
+
+// insertEnrichmentVersion stores enriched data under the next version number of the idea
+func insertEnrichmentVersion(tx *sql.Tx, id int64, enriched *model.EnrichedIdea, modelName, promptHash string) (int, error) {
+	enrichedJSON, err := json.Marshal(enriched)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM enrichment_versions WHERE idea_id = ?`, id).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO enrichment_versions (idea_id, version, enriched_json, model, prompt_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.Exec(query, id, version, string(enrichedJSON), modelName, promptHash, timestamp(time.Now())); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func scanEnrichmentVersion(row rowScanner) (*model.EnrichmentVersion, error) {
+	v := &model.EnrichmentVersion{}
+	if err := row.Scan(&v.ID, &v.IdeaID, &v.Version, &v.EnrichedJSON, &v.Model, &v.PromptHash, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := v.ParseEnriched(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// updateEnriched writes enriched data into the idea row and marks which version it came from
+func updateEnriched(tx *sql.Tx, id int64, enriched *model.EnrichedIdea, version int) error {
+	enrichedJSON, err := json.Marshal(enriched)
+	if err != nil {
+		return err
+	}
+
+	affectedReposJSON, err := json.Marshal(enriched.AffectedComponents)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE ideas SET
+			enriched_json = ?,
+			title = ?,
+			category = ?,
+			priority = ?,
+			complexity = ?,
+			affected_repos = ?,
+			enrichment_version = ?,
+			updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err = tx.Exec(query,
+		string(enrichedJSON),
+		enriched.Title,
+		enriched.Category,
+		enriched.Priority,
+		enriched.Complexity,
+		string(affectedReposJSON),
+		version,
+		timestamp(time.Now()),
+		id,
+	)
+	return err
+}
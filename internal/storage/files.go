@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// FileStore keeps attachment contents outside the database
+type FileStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FileStoreOptions selects and configures the file store
+type FileStoreOptions struct {
+	// Backend is "local" or "s3"
+	Backend string
+	Dir     string
+
+	S3Endpoint  string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3Region    string
+	S3UseSSL    bool
+}
+
+var files FileStore
+
+// InitFiles initializes the attachment file store
+func InitFiles(opts FileStoreOptions) error {
+	switch opts.Backend {
+	case "", "local":
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return fmt.Errorf("failed to create attachments directory: %w", err)
+		}
+		files = &localStore{dir: opts.Dir}
+		log.Printf("Attachments stored in %s", opts.Dir)
+	case "s3":
+		store, err := newS3Store(opts)
+		if err != nil {
+			return err
+		}
+		files = store
+		log.Printf("Attachments stored in bucket %s at %s", opts.S3Bucket, opts.S3Endpoint)
+	default:
+		return fmt.Errorf("unknown attachments storage %q: expected local or s3", opts.Backend)
+	}
+	return nil
+}
+
+// Files returns the attachment file store, nil until InitFiles is called
+func Files() FileStore {
+	return files
+}
+
+// localStore keeps files in a directory on disk
+type localStore struct {
+	dir string
+}
+
+func (s *localStore) path(key string) (string, error) {
+	// Keys are generated by the repository, reject anything that could escape the directory
+	if key == "" || strings.Contains(key, "..") || filepath.IsAbs(key) {
+		return "", fmt.Errorf("invalid file key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (s *localStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// s3Store keeps files in an S3-compatible bucket (AWS S3, MinIO)
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func newS3Store(opts FileStoreOptions) (*s3Store, error) {
+	if opts.S3Endpoint == "" || opts.S3Bucket == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for s3 attachments storage")
+	}
+
+	client, err := minio.New(opts.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.S3AccessKey, opts.S3SecretKey, ""),
+		Secure: opts.S3UseSSL,
+		Region: opts.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, opts.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", opts.S3Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, opts.S3Bucket, minio.MakeBucketOptions{Region: opts.S3Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", opts.S3Bucket, err)
+		}
+	}
+
+	return &s3Store{client: client, bucket: opts.S3Bucket}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy, Stat surfaces a missing object before the response starts
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// ErrInvalidCursor is returned by ListPage for cursors it did not produce,
+// or produced for another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// defaultPageSize is used by ListPage when the filter has no limit
+const defaultPageSize = 50
+
+// ideaSort describes how a list is ordered by one key. Rows with equal keys
+// are ordered by ID, newest first, which makes the order total and keyset pagination stable.
+type ideaSort struct {
+	expr string                        // SQL expression of the sort key
+	desc bool                          // whether the key is sorted in descending order
+	key  func(*model.Idea) interface{} // the key of a loaded idea, must match expr
+}
+
+var ideaSorts = map[model.IdeaSort]ideaSort{
+	model.SortCreated: {"created_at", true, func(i *model.Idea) interface{} { return timestamp(i.CreatedAt) }},
+	model.SortUpdated: {"updated_at", true, func(i *model.Idea) interface{} { return timestamp(i.UpdatedAt) }},
+	model.SortPriority: {rankExpr("priority", model.AllPriorities(), 0), true, func(i *model.Idea) interface{} {
+		return rank(i.Priority, model.AllPriorities(), 0)
+	}},
+	model.SortVotes: {votesExpr, true, func(i *model.Idea) interface{} { return i.Votes }},
+	// Ideas of unknown complexity go after epics
+	model.SortComplexity: {rankExpr("complexity", model.AllComplexities(), 99), false, func(i *model.Idea) interface{} {
+		return rank(i.Complexity, model.AllComplexities(), 99)
+	}},
+	model.SortScore: {"score", true, func(i *model.Idea) interface{} { return i.Score }},
+}
+
+// votesExpr counts the votes of an idea, for the votes column and the votes sort
+const votesExpr = "(SELECT COUNT(*) FROM idea_votes v WHERE v.idea_id = ideas.id)"
+
+// sortOf returns the sort for a list order, newest first when it is empty or unknown
+func sortOf(s model.IdeaSort) ideaSort {
+	if sort, ok := ideaSorts[s]; ok {
+		return sort
+	}
+	return ideaSorts[model.SortCreated]
+}
+
+// orderBy returns the ORDER BY clause, reversed when paging backwards
+func (s ideaSort) orderBy(reverse bool) string {
+	keyDir, idDir := "ASC", "DESC"
+	if s.desc {
+		keyDir = "DESC"
+	}
+	if reverse {
+		keyDir, idDir = flip(keyDir), flip(idDir)
+	}
+	return s.expr + " " + keyDir + ", id " + idDir
+}
+
+// after returns the condition selecting rows that follow the cursor position,
+// or precede it when paging backwards
+func (s ideaSort) after(reverse bool) string {
+	keyOp, idOp := ">", "<"
+	if s.desc {
+		keyOp = "<"
+	}
+	if reverse {
+		keyOp, idOp = flipOp(keyOp), flipOp(idOp)
+	}
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", s.expr, keyOp, s.expr, idOp)
+}
+
+func flip(dir string) string {
+	if dir == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func flipOp(op string) string {
+	if op == "<" {
+		return ">"
+	}
+	return "<"
+}
+
+// rankExpr builds a CASE expression numbering the values of an enum column from 1 in the
+// order they are listed; other values get unknown
+func rankExpr[T ~string](column string, values []T, unknown int) string {
+	expr := "CASE " + column
+	for i, v := range values {
+		expr += fmt.Sprintf(" WHEN '%s' THEN %d", v, i+1)
+	}
+	return expr + fmt.Sprintf(" ELSE %d END", unknown)
+}
+
+// rank is the Go counterpart of rankExpr
+func rank[T ~string](value T, values []T, unknown int) int {
+	for i, v := range values {
+		if v == value {
+			return i + 1
+		}
+	}
+	return unknown
+}
+
+// pageCursor is the position of a page boundary: the sort key and ID of the boundary row
+type pageCursor struct {
+	Sort     model.IdeaSort `json:"s"`
+	Key      interface{}    `json:"k"`
+	ID       int64          `json:"id"`
+	Backward bool           `json:"b,omitempty"` // the page ends before the row
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return c, ErrInvalidCursor
+	}
+	switch c.Key.(type) {
+	case string, float64:
+	default:
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ListPage returns one page of ideas matching the filter, starting at filter.Cursor.
+// Unlike offsets, cursors keep pointing at the same place when ideas are added or removed.
+func (r *IdeaRepository) ListPage(filter model.IdeaFilter) (*model.IdeaPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = model.SortCreated
+	}
+	sort := sortOf(filter.Sort)
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	var cursor *pageCursor
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != filter.Sort {
+			return nil, ErrInvalidCursor
+		}
+		cursor = &c
+	}
+	backward := cursor != nil && cursor.Backward
+
+	where, args := ideaConditions(filter)
+	if cursor != nil {
+		if where == "" {
+			where = " WHERE "
+		} else {
+			where += " AND "
+		}
+		where += sort.after(backward)
+		args = append(args, cursor.Key, cursor.Key, cursor.ID)
+	}
+
+	// One extra row tells whether there is another page in this direction
+	query := `SELECT ` + ideaColumns + ` FROM ideas` + where + ` ORDER BY ` + sort.orderBy(backward) + ` LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	ideas, err := scanIdeas(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(ideas) > limit
+	if backward && !more {
+		// Nothing precedes these rows, so this is the first page; load it in full
+		filter.Cursor = ""
+		return r.ListPage(filter)
+	}
+	if more {
+		ideas = ideas[:limit]
+	}
+	if backward {
+		for i, j := 0, len(ideas)-1; i < j; i, j = i+1, j-1 {
+			ideas[i], ideas[j] = ideas[j], ideas[i]
+		}
+	}
+
+	page := &model.IdeaPage{Ideas: ideas}
+	if len(ideas) == 0 {
+		return page, nil
+	}
+
+	hasNext, hasPrev := more, cursor != nil
+	if backward {
+		hasNext, hasPrev = true, true
+	}
+	boundary := func(idea *model.Idea, backward bool) string {
+		return pageCursor{Sort: filter.Sort, Key: sort.key(idea), ID: idea.ID, Backward: backward}.encode()
+	}
+	if hasNext {
+		page.NextCursor = boundary(ideas[len(ideas)-1], false)
+	}
+	if hasPrev {
+		page.PrevCursor = boundary(ideas[0], true)
+	}
+	return page, nil
+}
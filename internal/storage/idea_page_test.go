@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// initTestDB opens an empty database for a test
+func initTestDB(t *testing.T) {
+	t.Helper()
+	config.Load()
+	if err := Init(t.TempDir() + "/ideas.db"); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	t.Cleanup(func() { Close() })
+}
+
+// createIdeas creates ideas in a chat and returns their IDs in creation order
+func createIdeas(t *testing.T, texts ...string) []int64 {
+	t.Helper()
+	repo := NewIdeaRepository()
+	ids := make([]int64, len(texts))
+	for i, text := range texts {
+		idea, err := repo.Create(model.CreateIdeaInput{TelegramChatID: -100, TelegramUserID: 1, RawText: text})
+		if err != nil {
+			t.Fatalf("create idea: %v", err)
+		}
+		ids[i] = idea.ID
+	}
+	return ids
+}
+
+func TestListPageByVotes(t *testing.T) {
+	initTestDB(t)
+	repo := NewIdeaRepository()
+	votes := NewVoteRepository()
+
+	ids := createIdeas(t, "a", "b", "c", "d", "e")
+	// Votes per idea: a 1, b 3, c 0, d 1, e 2
+	for i, n := range []int{1, 3, 0, 1, 2} {
+		for user := range n {
+			if _, err := votes.Toggle(ids[i], int64(user+1)); err != nil {
+				t.Fatalf("vote: %v", err)
+			}
+		}
+	}
+	// Most voted first, ties newest first
+	want := []int64{ids[1], ids[4], ids[3], ids[0], ids[2]}
+
+	var got []int64
+	var pages []*model.IdeaPage
+	filter := model.IdeaFilter{Sort: model.SortVotes, Limit: 2}
+	for {
+		page, err := repo.ListPage(filter)
+		if err != nil {
+			t.Fatalf("ListPage: %v", err)
+		}
+		pages = append(pages, page)
+		for _, idea := range page.Ideas {
+			got = append(got, idea.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("paging forward = %v, want %v", got, want)
+	}
+
+	// Paging back from the last page returns the page before it
+	filter.Cursor = pages[len(pages)-1].PrevCursor
+	page, err := repo.ListPage(filter)
+	if err != nil {
+		t.Fatalf("ListPage backward: %v", err)
+	}
+	var back []int64
+	for _, idea := range page.Ideas {
+		back = append(back, idea.ID)
+	}
+	if !slices.Equal(back, want[2:4]) {
+		t.Errorf("paging backward = %v, want %v", back, want[2:4])
+	}
+
+	// A cursor of another sort order is rejected
+	filter.Sort = model.SortCreated
+	if _, err := repo.ListPage(filter); err != ErrInvalidCursor {
+		t.Errorf("ListPage with a votes cursor sorted by date = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -3,6 +3,9 @@ package storage
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,11 +22,34 @@ func NewIdeaRepository() *IdeaRepository {
 
 // Create inserts a new idea
 func (r *IdeaRepository) Create(input model.CreateIdeaInput) (*model.Idea, error) {
+	var clarificationsJSON []byte
+	if len(input.Clarifications) > 0 {
+		var err error
+		clarificationsJSON, err = json.Marshal(input.Clarifications)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	source := input.Source
+	if source == "" {
+		source = model.SourceCommand
+	}
+
+	// A category preset for the topic counts as a manual edit, so re-enrichment keeps it
+	overridden := ""
+	if input.Category != "" {
+		overridden = `["category"]`
+	}
+
 	query := `
 		INSERT INTO ideas (
 			telegram_message_id, telegram_chat_id, telegram_user_id,
-			telegram_username, telegram_first_name, raw_text, status
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+			telegram_username, telegram_first_name, raw_text, clarifications, status,
+			source, original_author_id, original_author_username, original_author_name,
+			source_chat_id, source_message_id, source_url,
+			message_thread_id, message_chat_id, category, overridden_fields, project_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.Exec(query,
@@ -33,7 +59,20 @@ func (r *IdeaRepository) Create(input model.CreateIdeaInput) (*model.Idea, error
 		input.TelegramUsername,
 		input.TelegramFirstName,
 		input.RawText,
-		model.StatusNew,
+		string(clarificationsJSON),
+		model.CurrentWorkflow().Initial,
+		string(source),
+		input.OriginalAuthor.TelegramUserID,
+		input.OriginalAuthor.TelegramUsername,
+		input.OriginalAuthor.Name,
+		input.SourceChatID,
+		input.SourceMessageID,
+		input.SourceURL,
+		input.MessageThreadID,
+		input.MessageChatID,
+		string(input.Category),
+		overridden,
+		input.ProjectID,
 	)
 	if err != nil {
 		return nil, err
@@ -47,20 +86,32 @@ func (r *IdeaRepository) Create(input model.CreateIdeaInput) (*model.Idea, error
 	return r.GetByID(id)
 }
 
-// GetByID retrieves an idea by ID
-func (r *IdeaRepository) GetByID(id int64) (*model.Idea, error) {
-	query := `
-		SELECT id, telegram_message_id, telegram_chat_id, telegram_user_id,
-			telegram_username, telegram_first_name, raw_text, enriched_json,
-			title, category, priority, complexity, affected_repos, status,
-			admin_notes, created_at, updated_at
-		FROM ideas WHERE id = ?
-	`
+// ideaColumns is the column list shared by every query that loads full ideas
+const ideaColumns = `
+	id, telegram_message_id, telegram_chat_id, telegram_user_id,
+	telegram_username, telegram_first_name, raw_text, enriched_json,
+	title, category, priority, complexity, affected_repos, status,
+	admin_notes, clarifications, enrichment_version, overridden_fields,
+	source, original_author_id, original_author_username, original_author_name,
+	source_chat_id, source_message_id, source_url, message_thread_id, message_chat_id, project_id, assignee,
+	reach, impact, confidence, effort, score, merged_into_id,
+	created_at, updated_at,
+	(SELECT json_group_array(json_object('id', t.id, 'name', t.name, 'color', t.color))
+		FROM idea_tags it JOIN tags t ON t.id = it.tag_id WHERE it.idea_id = ideas.id),
+	` + votesExpr + `
+`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanIdea scans a row selected with ideaColumns
+func scanIdea(row rowScanner) (*model.Idea, error) {
 	idea := &model.Idea{}
-	var affectedReposStr string
+	var affectedReposStr, clarificationsStr, overriddenStr, tagsStr string
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&idea.ID,
 		&idea.TelegramMessageID,
 		&idea.TelegramChatID,
@@ -76,8 +127,30 @@ func (r *IdeaRepository) GetByID(id int64) (*model.Idea, error) {
 		&affectedReposStr,
 		&idea.Status,
 		&idea.AdminNotes,
+		&clarificationsStr,
+		&idea.EnrichmentVersion,
+		&overriddenStr,
+		&idea.Source,
+		&idea.OriginalAuthor.TelegramUserID,
+		&idea.OriginalAuthor.TelegramUsername,
+		&idea.OriginalAuthor.Name,
+		&idea.SourceChatID,
+		&idea.SourceMessageID,
+		&idea.SourceURL,
+		&idea.MessageThreadID,
+		&idea.MessageChatID,
+		&idea.ProjectID,
+		&idea.Assignee,
+		&idea.Scoring.Reach,
+		&idea.Scoring.Impact,
+		&idea.Scoring.Confidence,
+		&idea.Scoring.Effort,
+		&idea.Score,
+		&idea.MergedIntoID,
 		&idea.CreatedAt,
 		&idea.UpdatedAt,
+		&tagsStr,
+		&idea.Votes,
 	)
 	if err != nil {
 		return nil, err
@@ -88,67 +161,218 @@ func (r *IdeaRepository) GetByID(id int64) (*model.Idea, error) {
 		_ = json.Unmarshal([]byte(affectedReposStr), &idea.AffectedComponents)
 	}
 
+	if clarificationsStr != "" {
+		_ = json.Unmarshal([]byte(clarificationsStr), &idea.Clarifications)
+	}
+
+	if overriddenStr != "" {
+		_ = json.Unmarshal([]byte(overriddenStr), &idea.OverriddenFields)
+	}
+
+	if tagsStr != "" {
+		_ = json.Unmarshal([]byte(tagsStr), &idea.Tags)
+		sort.Slice(idea.Tags, func(i, j int) bool { return idea.Tags[i].Name < idea.Tags[j].Name })
+	}
+
 	// Parse enriched data
 	_ = idea.ParseEnriched()
 
 	return idea, nil
 }
 
+// scanIdeas scans all rows selected with ideaColumns
+func scanIdeas(rows *sql.Rows) ([]*model.Idea, error) {
+	defer rows.Close()
+
+	var ideas []*model.Idea
+	for rows.Next() {
+		idea, err := scanIdea(rows)
+		if err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+
+	return ideas, rows.Err()
+}
+
+// GetByID retrieves an idea by ID
+func (r *IdeaRepository) GetByID(id int64) (*model.Idea, error) {
+	query := `SELECT ` + ideaColumns + ` FROM ideas WHERE id = ?`
+	return scanIdea(r.db.QueryRow(query, id))
+}
+
 // List retrieves ideas with optional filters
 func (r *IdeaRepository) List(filter model.IdeaFilter) ([]*model.Idea, error) {
-	query := `
-		SELECT id, telegram_message_id, telegram_chat_id, telegram_user_id,
-			telegram_username, telegram_first_name, raw_text, enriched_json,
-			title, category, priority, complexity, affected_repos, status,
-			admin_notes, created_at, updated_at
-		FROM ideas
-	`
+	where, args := ideaConditions(filter)
+	query := `SELECT ` + ideaColumns + ` FROM ideas` + where + ` ORDER BY ` + sortOf(filter.Sort).orderBy(false)
+
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
 
-	var conditions []string
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIdeas(rows)
+}
+
+// ideaConditions builds the WHERE clause for every field of the filter except
+// the sort order, limit and cursor, so lists and counts always agree.
+// Ideas merged into another one are never listed.
+func ideaConditions(filter model.IdeaFilter) (string, []interface{}) {
+	conditions := []string{"merged_into_id = 0"}
 	var args []interface{}
 
-	if len(filter.Status) > 0 {
-		placeholders := make([]string, len(filter.Status))
-		for i, s := range filter.Status {
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
 			placeholders[i] = "?"
-			args = append(args, string(s))
+			args = append(args, v)
 		}
-		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ",")+")")
+		conditions = append(conditions, column+" IN ("+strings.Join(placeholders, ",")+")")
 	}
+	in("status", stringValues(filter.Status))
+	in("category", stringValues(filter.Category))
+	in("priority", stringValues(filter.Priority))
 
-	if len(filter.Category) > 0 {
-		placeholders := make([]string, len(filter.Category))
-		for i, c := range filter.Category {
-			placeholders[i] = "?"
-			args = append(args, string(c))
-		}
-		conditions = append(conditions, "category IN ("+strings.Join(placeholders, ",")+")")
+	if filter.TelegramChatID != 0 {
+		conditions = append(conditions, "telegram_chat_id = ?")
+		args = append(args, filter.TelegramChatID)
 	}
 
-	if len(filter.Priority) > 0 {
-		placeholders := make([]string, len(filter.Priority))
-		for i, p := range filter.Priority {
-			placeholders[i] = "?"
-			args = append(args, string(p))
-		}
-		conditions = append(conditions, "priority IN ("+strings.Join(placeholders, ",")+")")
+	if filter.ProjectID != 0 {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, filter.ProjectID)
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if filter.Assignee != "" {
+		conditions = append(conditions, "assignee = ? COLLATE NOCASE")
+		args = append(args, filter.Assignee)
 	}
 
-	query += " ORDER BY created_at DESC"
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "id IN (SELECT it.idea_id FROM idea_tags it JOIN tags t ON t.id = it.tag_id WHERE t.name = ?)")
+		args = append(args, tag)
+	}
 
-	if filter.Limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, filter.Limit)
+	if filter.MinScore > 0 {
+		conditions = append(conditions, "score >= ?")
+		args = append(args, filter.MinScore)
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, timestamp(filter.CreatedFrom))
+	}
+
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, timestamp(filter.CreatedTo))
+	}
+
+	if !filter.UpdatedBefore.IsZero() {
+		conditions = append(conditions, "updated_at < ?")
+		args = append(args, timestamp(filter.UpdatedBefore))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// stringValues converts a slice of string-based enum values for use as query arguments
+func stringValues[T ~string](values []T) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = string(v)
 	}
+	return result
+}
 
-	if filter.Offset > 0 {
-		query += " OFFSET ?"
-		args = append(args, filter.Offset)
+// openStatusCondition builds a condition matching ideas in a status the current
+// workflow doesn't mark terminal, with its arguments
+func openStatusCondition(column string) (string, []interface{}) {
+	open := model.CurrentWorkflow().OpenStatuses()
+	if len(open) == 0 {
+		return "0", nil
+	}
+	placeholders := make([]string, len(open))
+	args := make([]interface{}, len(open))
+	for i, s := range open {
+		placeholders[i] = "?"
+		args[i] = string(s)
+	}
+	return column + " IN (" + strings.Join(placeholders, ",") + ")", args
+}
+
+// UpdateStatus updates the status of an idea and records the transition with its comment in status history
+func (r *IdeaRepository) UpdateStatus(id int64, status model.IdeaStatus, comment string) error {
+	return r.BulkUpdateStatus([]int64{id}, status, comment)
+}
+
+// BulkUpdateStatus moves several ideas to a status in one transaction, recording each
+// transition with the same comment; either every idea is updated or none
+func (r *IdeaRepository) BulkUpdateStatus(ids []int64, status model.IdeaStatus, comment string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
+
+	// Themes group open ideas, an idea leaves its theme once it is finished
+	state := model.CurrentWorkflow().State(status)
+	terminal := state != nil && state.Terminal
+
+	now := timestamp(time.Now())
+	for _, id := range ids {
+		var current model.IdeaStatus
+		if err := tx.QueryRow(`SELECT status FROM ideas WHERE id = ?`, id).Scan(&current); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`UPDATE ideas SET status = ?, updated_at = ? WHERE id = ?`, string(status), now, id); err != nil {
+			return err
+		}
+
+		if current != status {
+			query := `INSERT INTO idea_status_history (idea_id, from_status, to_status, comment, changed_at) VALUES (?, ?, ?, ?, ?)`
+			if _, err := tx.Exec(query, id, string(current), string(status), comment, now); err != nil {
+				return err
+			}
+		}
+
+		if terminal {
+			if _, err := tx.Exec(`DELETE FROM idea_themes WHERE idea_id = ?`, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListStatusChanges returns status transitions that happened in [from, to).
+// A non-zero chatID limits the result to ideas from that Telegram chat.
+func (r *IdeaRepository) ListStatusChanges(from, to time.Time, chatID int64) ([]model.StatusChange, error) {
+	query := `
+		SELECT h.idea_id, COALESCE(NULLIF(i.title, ''), i.raw_text), h.from_status, h.to_status, h.changed_at
+		FROM idea_status_history h
+		JOIN ideas i ON i.id = h.idea_id
+		WHERE h.changed_at >= ? AND h.changed_at < ?
+	`
+	args := []interface{}{timestamp(from), timestamp(to)}
+
+	if chatID != 0 {
+		query += " AND i.telegram_chat_id = ?"
+		args = append(args, chatID)
+	}
+
+	query += " ORDER BY h.changed_at ASC"
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -156,133 +380,195 @@ func (r *IdeaRepository) List(filter model.IdeaFilter) ([]*model.Idea, error) {
 	}
 	defer rows.Close()
 
-	var ideas []*model.Idea
+	var changes []model.StatusChange
 	for rows.Next() {
-		idea := &model.Idea{}
-		var affectedReposStr string
-
-		err := rows.Scan(
-			&idea.ID,
-			&idea.TelegramMessageID,
-			&idea.TelegramChatID,
-			&idea.TelegramUserID,
-			&idea.TelegramUsername,
-			&idea.TelegramFirstName,
-			&idea.RawText,
-			&idea.EnrichedJSON,
-			&idea.Title,
-			&idea.Category,
-			&idea.Priority,
-			&idea.Complexity,
-			&affectedReposStr,
-			&idea.Status,
-			&idea.AdminNotes,
-			&idea.CreatedAt,
-			&idea.UpdatedAt,
-		)
-		if err != nil {
+		var c model.StatusChange
+		if err := rows.Scan(&c.IdeaID, &c.IdeaTitle, &c.FromStatus, &c.ToStatus, &c.ChangedAt); err != nil {
 			return nil, err
 		}
-
-		if affectedReposStr != "" {
-			_ = json.Unmarshal([]byte(affectedReposStr), &idea.AffectedComponents)
-		}
-		_ = idea.ParseEnriched()
-
-		ideas = append(ideas, idea)
+		changes = append(changes, c)
 	}
 
-	return ideas, rows.Err()
+	return changes, rows.Err()
 }
 
-// UpdateEnriched updates the enriched data for an idea
-func (r *IdeaRepository) UpdateEnriched(id int64, enriched *model.EnrichedIdea) error {
-	enrichedJSON, err := json.Marshal(enriched)
+// ListStatusHistory returns the status transitions of an idea, oldest first
+func (r *IdeaRepository) ListStatusHistory(id int64) ([]model.StatusChange, error) {
+	query := `
+		SELECT idea_id, from_status, to_status, comment, changed_at
+		FROM idea_status_history
+		WHERE idea_id = ?
+		ORDER BY changed_at ASC, id ASC
+	`
+	rows, err := r.db.Query(query, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.StatusChange
+	for rows.Next() {
+		var c model.StatusChange
+		if err := rows.Scan(&c.IdeaID, &c.FromStatus, &c.ToStatus, &c.Comment, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
 	}
 
-	affectedReposJSON, err := json.Marshal(enriched.AffectedComponents)
+	return changes, rows.Err()
+}
+
+// UpdateAssignee sets the Telegram username responsible for an idea, empty to unassign
+func (r *IdeaRepository) UpdateAssignee(id int64, assignee string) error {
+	query := `UPDATE ideas SET assignee = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.Exec(query, assignee, timestamp(time.Now()), id)
+	return err
+}
+
+// BulkUpdateAssignee sets the assignee of several ideas in one transaction
+func (r *IdeaRepository) BulkUpdateAssignee(ids []int64, assignee string) error {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	query := `
-		UPDATE ideas SET
-			enriched_json = ?,
-			title = ?,
-			category = ?,
-			priority = ?,
-			complexity = ?,
-			affected_repos = ?,
-			updated_at = ?
-		WHERE id = ?
-	`
+	now := timestamp(time.Now())
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE ideas SET assignee = ?, updated_at = ? WHERE id = ?`, assignee, now, id); err != nil {
+			return err
+		}
+	}
 
-	_, err = r.db.Exec(query,
-		string(enrichedJSON),
-		enriched.Title,
-		enriched.Category,
-		enriched.Priority,
-		enriched.Complexity,
-		string(affectedReposJSON),
-		time.Now(),
-		id,
-	)
-	return err
+	return tx.Commit()
 }
 
-// UpdateStatus updates the status of an idea
-func (r *IdeaRepository) UpdateStatus(id int64, status model.IdeaStatus) error {
-	query := `UPDATE ideas SET status = ?, updated_at = ? WHERE id = ?`
-	_, err := r.db.Exec(query, string(status), time.Now(), id)
+// UpdateScoring stores the RICE factors of an idea together with the computed score.
+// A non-nil overridden list also replaces the fields protected from re-enrichment.
+func (r *IdeaRepository) UpdateScoring(id int64, scoring model.Scoring, overridden []string) error {
+	query := `UPDATE ideas SET reach = ?, impact = ?, confidence = ?, effort = ?, score = ?, updated_at = ?`
+	args := []interface{}{scoring.Reach, scoring.Impact, scoring.Confidence, scoring.Effort, scoring.Score(), timestamp(time.Now())}
+
+	if overridden != nil {
+		overriddenJSON, err := json.Marshal(overridden)
+		if err != nil {
+			return err
+		}
+		query += `, overridden_fields = ?`
+		args = append(args, string(overriddenJSON))
+	}
+
+	query += ` WHERE id = ?`
+	args = append(args, id)
+
+	_, err := r.db.Exec(query, args...)
 	return err
 }
 
 // UpdateAdminNotes updates the admin notes for an idea
 func (r *IdeaRepository) UpdateAdminNotes(id int64, notes string) error {
 	query := `UPDATE ideas SET admin_notes = ?, updated_at = ? WHERE id = ?`
-	_, err := r.db.Exec(query, notes, time.Now(), id)
+	_, err := r.db.Exec(query, notes, timestamp(time.Now()), id)
 	return err
 }
 
 // Count returns the total number of ideas matching the filter
 func (r *IdeaRepository) Count(filter model.IdeaFilter) (int, error) {
-	query := `SELECT COUNT(*) FROM ideas`
+	where, args := ideaConditions(filter)
 
-	var conditions []string
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM ideas`+where, args...).Scan(&count)
+	return count, err
+}
+
+// CountByStatus returns the number of ideas in each status.
+// A non-zero chatID limits the result to ideas from that Telegram chat.
+func (r *IdeaRepository) CountByStatus(chatID int64) (map[model.IdeaStatus]int, error) {
+	query := `SELECT status, COUNT(*) FROM ideas WHERE merged_into_id = 0`
 	var args []interface{}
 
-	if len(filter.Status) > 0 {
-		placeholders := make([]string, len(filter.Status))
-		for i, s := range filter.Status {
-			placeholders[i] = "?"
-			args = append(args, string(s))
-		}
-		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ",")+")")
+	if chatID != 0 {
+		query += " AND telegram_chat_id = ?"
+		args = append(args, chatID)
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " GROUP BY status"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	var count int
-	err := r.db.QueryRow(query, args...).Scan(&count)
-	return count, err
+	counts := make(map[model.IdeaStatus]int)
+	for rows.Next() {
+		var status model.IdeaStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
 }
 
-// Delete removes an idea by ID
+// Delete removes an idea by ID together with its dependent rows
 func (r *IdeaRepository) Delete(id int64) error {
-	query := `DELETE FROM ideas WHERE id = ?`
-	_, err := r.db.Exec(query, id)
-	return err
+	return r.BulkDelete([]int64{id})
 }
 
-// ListSummaries returns lightweight list of ideas for duplicate checking
-func (r *IdeaRepository) ListSummaries() ([]model.IdeaSummary, error) {
-	query := `SELECT id, title, raw_text FROM ideas WHERE status NOT IN ('rejected', 'implemented') ORDER BY created_at DESC LIMIT 100`
+// BulkDelete removes several ideas with their dependent rows in one transaction
+func (r *IdeaRepository) BulkDelete(ids []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	rows, err := r.db.Query(query)
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM idea_status_history WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM idea_themes WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM enrichment_versions WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM attachments WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM idea_tags WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM idea_votes WHERE idea_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM idea_merges WHERE idea_id = ? OR merged_id = ?`, id, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM idea_links WHERE from_id = ? OR to_id = ?`, id, id); err != nil {
+			return err
+		}
+		// Duplicates merged into a deleted idea are listed again on their own
+		if _, err := tx.Exec(`UPDATE ideas SET merged_into_id = 0 WHERE merged_into_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM ideas WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListSummaries returns lightweight list of open ideas of a project for duplicate checking
+func (r *IdeaRepository) ListSummaries(projectID int64) ([]model.IdeaSummary, error) {
+	open, args := openStatusCondition("status")
+	query := `SELECT id, title, raw_text FROM ideas WHERE ` + open + ` AND project_id = ? AND merged_into_id = 0 ORDER BY created_at DESC LIMIT 100`
+
+	rows, err := r.db.Query(query, append(args, projectID)...)
 	if err != nil {
 		return nil, err
 	}
@@ -299,3 +585,229 @@ func (r *IdeaRepository) ListSummaries() ([]model.IdeaSummary, error) {
 
 	return summaries, rows.Err()
 }
+
+// Merge marks an idea as a duplicate of another one in a single transaction. The
+// duplicate's text and status comments are kept in the other idea's merge history
+// and its notes are added to the other idea's notes. Its votes, attachments, tags
+// and links move over, and so do the duplicates merged into it before.
+func (r *IdeaRepository) Merge(id, intoID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	comments, err := statusComments(tx, id)
+	if err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	query := `
+		INSERT INTO idea_merges (idea_id, merged_id, raw_text, author, comments, merged_at)
+		SELECT ?, id, raw_text,
+			CASE WHEN telegram_username != '' THEN '@' || telegram_username ELSE telegram_first_name END, ?, ?
+		FROM ideas WHERE id = ?
+	`
+	if _, err := tx.Exec(query, intoID, comments, now, id); err != nil {
+		return err
+	}
+	var notes string
+	if err := tx.QueryRow(`SELECT admin_notes FROM ideas WHERE id = ?`, id).Scan(&notes); err != nil {
+		return err
+	}
+	if notes = strings.TrimSpace(notes); notes != "" {
+		notes = fmt.Sprintf("#%d: %s", id, notes)
+		query = `UPDATE ideas SET admin_notes = CASE WHEN admin_notes = '' THEN ? ELSE admin_notes || char(10) || char(10) || ? END WHERE id = ?`
+		if _, err := tx.Exec(query, notes, notes, intoID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE idea_merges SET idea_id = ? WHERE idea_id = ?`, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE ideas SET merged_into_id = ? WHERE merged_into_id = ?`, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE attachments SET idea_id = ? WHERE idea_id = ?`, intoID, id); err != nil {
+		return err
+	}
+	query = `INSERT OR IGNORE INTO idea_tags (idea_id, tag_id) SELECT ?, tag_id FROM idea_tags WHERE idea_id = ?`
+	if _, err := tx.Exec(query, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM idea_tags WHERE idea_id = ?`, id); err != nil {
+		return err
+	}
+	// A user who voted for both ideas keeps one vote
+	query = `
+		INSERT OR IGNORE INTO idea_votes (idea_id, telegram_user_id, created_at)
+		SELECT ?, telegram_user_id, created_at FROM idea_votes WHERE idea_id = ?
+	`
+	if _, err := tx.Exec(query, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM idea_votes WHERE idea_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM idea_themes WHERE idea_id = ?`, id); err != nil {
+		return err
+	}
+	if err := mergeParentLinks(tx, id, intoID); err != nil {
+		return err
+	}
+	// Links move over unless the other idea has the same one; links between the two are dropped
+	if _, err := tx.Exec(`UPDATE OR IGNORE idea_links SET from_id = ? WHERE from_id = ?`, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE OR IGNORE idea_links SET to_id = ? WHERE to_id = ?`, intoID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM idea_links WHERE from_id = ? OR to_id = ? OR from_id = to_id`, id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE ideas SET merged_into_id = ?, updated_at = ? WHERE id = ?`, intoID, now, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE ideas SET updated_at = ? WHERE id = ?`, now, intoID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// statusComments returns the comments left on the status changes of an idea,
+// one per line with the status they were left for
+func statusComments(tx *sql.Tx, id int64) (string, error) {
+	query := `SELECT to_status, comment FROM idea_status_history WHERE idea_id = ? AND comment != '' ORDER BY changed_at ASC, id ASC`
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var status model.IdeaStatus
+		var comment string
+		if err := rows.Scan(&status, &comment); err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("«%s»: %s", status.Label(), comment))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// mergeParentLinks drops the epic links of a duplicate that cannot move to the idea
+// it is merged into: an idea is a part of one epic only and epics make no cycles
+func mergeParentLinks(tx *sql.Tx, id, intoID int64) error {
+	parent := string(model.LinkParent)
+	query := `DELETE FROM idea_links WHERE type = ? AND ((from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?))`
+	if _, err := tx.Exec(query, parent, id, intoID, intoID, id); err != nil {
+		return err
+	}
+
+	ancestors, err := linkAncestors(tx, intoID)
+	if err != nil {
+		return err
+	}
+
+	// A part of the duplicate that is an epic above the kept idea would make a cycle
+	rows, err := tx.Query(`SELECT to_id FROM idea_links WHERE from_id = ? AND type = ?`, id, parent)
+	if err != nil {
+		return err
+	}
+	var cyclic []int64
+	for rows.Next() {
+		var childID int64
+		if err := rows.Scan(&childID); err != nil {
+			rows.Close()
+			return err
+		}
+		if ancestors[childID] {
+			cyclic = append(cyclic, childID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, childID := range cyclic {
+		if _, err := tx.Exec(`DELETE FROM idea_links WHERE from_id = ? AND to_id = ? AND type = ?`, id, childID, parent); err != nil {
+			return err
+		}
+	}
+
+	// The kept idea stays in its own epic; the duplicate's epic is dropped when
+	// the kept idea has one or when the epic is itself a part of the kept idea
+	epicID, err := linkParent(tx, id)
+	if err != nil || epicID == 0 {
+		return err
+	}
+	drop := len(ancestors) > 0
+	if !drop {
+		epicAncestors, err := linkAncestors(tx, epicID)
+		if err != nil {
+			return err
+		}
+		drop = epicAncestors[intoID]
+	}
+	if drop {
+		if _, err := tx.Exec(`DELETE FROM idea_links WHERE to_id = ? AND type = ?`, id, parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkAncestors returns the epics an idea is a part of, directly or through other epics
+func linkAncestors(tx *sql.Tx, id int64) (map[int64]bool, error) {
+	ancestors := map[int64]bool{}
+	for {
+		parentID, err := linkParent(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if parentID == 0 || ancestors[parentID] {
+			return ancestors, nil
+		}
+		ancestors[parentID] = true
+		id = parentID
+	}
+}
+
+// linkParent returns the epic an idea is a part of, 0 when it has none
+func linkParent(tx *sql.Tx, id int64) (int64, error) {
+	var parentID int64
+	err := tx.QueryRow(`SELECT from_id FROM idea_links WHERE to_id = ? AND type = ?`, id, string(model.LinkParent)).Scan(&parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return parentID, err
+}
+
+// ListMerges returns the duplicates merged into an idea, oldest first
+func (r *IdeaRepository) ListMerges(id int64) ([]model.IdeaMerge, error) {
+	query := `
+		SELECT idea_id, merged_id, raw_text, author, comments, merged_at
+		FROM idea_merges
+		WHERE idea_id = ?
+		ORDER BY merged_at ASC, id ASC
+	`
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var merges []model.IdeaMerge
+	for rows.Next() {
+		var m model.IdeaMerge
+		if err := rows.Scan(&m.IdeaID, &m.MergedID, &m.RawText, &m.Author, &m.Comments, &m.MergedAt); err != nil {
+			return nil, err
+		}
+		merges = append(merges, m)
+	}
+
+	return merges, rows.Err()
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+func TestMerge(t *testing.T) {
+	initTestDB(t)
+	repo := NewIdeaRepository()
+	votes := NewVoteRepository()
+	tags := NewTagRepository()
+
+	ids := createIdeas(t, "Тёмная тема", "Ночной режим")
+	kept, duplicate := ids[0], ids[1]
+
+	// User 1 voted for both ideas and keeps one vote, user 2 only for the duplicate
+	for _, v := range []struct{ idea, user int64 }{{kept, 1}, {duplicate, 1}, {duplicate, 2}} {
+		if _, err := votes.Toggle(v.idea, v.user); err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+	}
+	if err := tags.AddToIdeas([]int64{duplicate}, "ui", ""); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+	if err := repo.UpdateAdminNotes(kept, "Обсудить с дизайном"); err != nil {
+		t.Fatalf("notes: %v", err)
+	}
+	if err := repo.UpdateAdminNotes(duplicate, "Просили в поддержке"); err != nil {
+		t.Fatalf("notes: %v", err)
+	}
+	if err := repo.UpdateStatus(duplicate, model.StatusReviewed, "Похоже на #1"); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if err := repo.UpdateStatus(duplicate, model.StatusAccepted, ""); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	if err := repo.Merge(duplicate, kept); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	idea, err := repo.GetByID(kept)
+	if err != nil {
+		t.Fatalf("get idea: %v", err)
+	}
+	if idea.Votes != 2 {
+		t.Errorf("votes = %d, want 2", idea.Votes)
+	}
+	if len(idea.Tags) != 1 || idea.Tags[0].Name != "ui" {
+		t.Errorf("tags = %v, want [ui]", idea.Tags)
+	}
+	wantNotes := "Обсудить с дизайном\n\n#2: Просили в поддержке"
+	if idea.AdminNotes != wantNotes {
+		t.Errorf("notes = %q, want %q", idea.AdminNotes, wantNotes)
+	}
+
+	merges, err := repo.ListMerges(kept)
+	if err != nil {
+		t.Fatalf("ListMerges: %v", err)
+	}
+	if len(merges) != 1 {
+		t.Fatalf("merges = %v, want one", merges)
+	}
+	m := merges[0]
+	if m.MergedID != duplicate || m.RawText != "Ночной режим" {
+		t.Errorf("merge = #%d %q, want #%d %q", m.MergedID, m.RawText, duplicate, "Ночной режим")
+	}
+	wantComments := "«" + model.StatusReviewed.Label() + "»: Похоже на #1"
+	if m.Comments != wantComments {
+		t.Errorf("comments = %q, want %q", m.Comments, wantComments)
+	}
+
+	merged, err := repo.GetByID(duplicate)
+	if err != nil {
+		t.Fatalf("get duplicate: %v", err)
+	}
+	if merged.MergedIntoID != kept || merged.Votes != 0 || len(merged.Tags) != 0 {
+		t.Errorf("duplicate = merged into %d, %d votes, tags %v; want merged into %d without votes and tags",
+			merged.MergedIntoID, merged.Votes, merged.Tags, kept)
+	}
+}
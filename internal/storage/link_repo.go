@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type LinkRepository struct {
+	db *sql.DB
+}
+
+func NewLinkRepository() *LinkRepository {
+	return &LinkRepository{db: DB()}
+}
+
+// Create links two ideas; for directed types fromID comes first
+func (r *LinkRepository) Create(fromID, toID int64, linkType model.LinkType) error {
+	query := `INSERT INTO idea_links (from_id, to_id, type, created_at) VALUES (?, ?, ?, ?)`
+	_, err := r.db.Exec(query, fromID, toID, string(linkType), timestamp(time.Now()))
+	return err
+}
+
+// Delete removes a link of an idea, links of other ideas are left alone
+func (r *LinkRepository) Delete(ideaID, linkID int64) error {
+	_, err := r.db.Exec(`DELETE FROM idea_links WHERE id = ? AND (from_id = ? OR to_id = ?)`, linkID, ideaID, ideaID)
+	return err
+}
+
+// ListByIdea returns the ideas linked to an idea in either direction
+func (r *LinkRepository) ListByIdea(id int64) ([]model.LinkedIdea, error) {
+	query := `
+		SELECT l.id, l.type, l.from_id = ?, i.id, COALESCE(NULLIF(i.title, ''), i.raw_text), i.status
+		FROM idea_links l
+		JOIN ideas i ON i.id = CASE WHEN l.from_id = ? THEN l.to_id ELSE l.from_id END
+		WHERE l.from_id = ? OR l.to_id = ?
+		ORDER BY l.id ASC
+	`
+	rows, err := r.db.Query(query, id, id, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []model.LinkedIdea
+	for rows.Next() {
+		var l model.LinkedIdea
+		var linkType model.LinkType
+		var outgoing bool
+		if err := rows.Scan(&l.LinkID, &linkType, &outgoing, &l.IdeaID, &l.Title, &l.Status); err != nil {
+			return nil, err
+		}
+		l.Relation = model.RelationOf(linkType, outgoing)
+		links = append(links, l)
+	}
+
+	return links, rows.Err()
+}
+
+// Linked reports whether two ideas are linked in any way
+func (r *LinkRepository) Linked(a, b int64) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM idea_links WHERE (from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?)`
+	err := r.db.QueryRow(query, a, b, b, a).Scan(&count)
+	return count > 0, err
+}
+
+// ParentOf returns the epic an idea is a part of, 0 when it has none
+func (r *LinkRepository) ParentOf(id int64) (int64, error) {
+	var parentID int64
+	err := r.db.QueryRow(`SELECT from_id FROM idea_links WHERE to_id = ? AND type = ?`, id, string(model.LinkParent)).Scan(&parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return parentID, err
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type ProjectRepository struct {
+	db *sql.DB
+}
+
+func NewProjectRepository() *ProjectRepository {
+	return &ProjectRepository{db: DB()}
+}
+
+const projectColumns = `
+	id, name, system_prompt, rate_limit_per_user, rate_limit_global,
+	web_username, web_password_hash, created_at, updated_at
+`
+
+// Create saves a new project together with its chats
+func (r *ProjectRepository) Create(p *model.Project) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := timestamp(time.Now())
+	result, err := tx.Exec(`
+		INSERT INTO projects (name, system_prompt, rate_limit_per_user, rate_limit_global,
+			web_username, web_password_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Name, p.SystemPrompt, p.RateLimitPerUser, p.RateLimitGlobal,
+		p.WebUsername, p.WebPasswordHash, now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertProjectChats(tx, id, p.Chats); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Update saves all fields of an existing project and replaces its chats.
+// The component catalog is edited with ComponentRepository.
+func (r *ProjectRepository) Update(p *model.Project) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE projects SET name = ?, system_prompt = ?, rate_limit_per_user = ?,
+			rate_limit_global = ?, web_username = ?, web_password_hash = ?, updated_at = ?
+		WHERE id = ?
+	`, p.Name, p.SystemPrompt, p.RateLimitPerUser, p.RateLimitGlobal,
+		p.WebUsername, p.WebPasswordHash, timestamp(time.Now()), p.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM project_chats WHERE project_id = ?`, p.ID); err != nil {
+		return err
+	}
+	if err := insertProjectChats(tx, p.ID, p.Chats); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertProjectChats(tx *sql.Tx, projectID int64, chats []model.ProjectChat) error {
+	for _, c := range chats {
+		if _, err := tx.Exec(`INSERT INTO project_chats (chat_id, thread_id, project_id) VALUES (?, ?, ?)`,
+			c.ChatID, c.ThreadID, projectID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a project. Its ideas are kept without a project.
+func (r *ProjectRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE ideas SET project_id = 0 WHERE project_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM project_chats WHERE project_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM components WHERE project_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a project with its chats and components
+func (r *ProjectRepository) GetByID(id int64) (*model.Project, error) {
+	p, err := scanProject(r.db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	return p, r.loadDetails(p)
+}
+
+// GetByWebUsername finds the project whose web login is username
+func (r *ProjectRepository) GetByWebUsername(username string) (*model.Project, error) {
+	p, err := scanProject(r.db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE web_username = ? AND web_username != ''`, username))
+	if err != nil {
+		return nil, err
+	}
+	return p, r.loadDetails(p)
+}
+
+// ForChat returns the project of a chat, preferring a project bound to the exact
+// forum topic over one bound to the whole chat. It returns nil when the chat has no project.
+func (r *ProjectRepository) ForChat(chatID, threadID int64) (*model.Project, error) {
+	var projectID int64
+	err := r.db.QueryRow(`
+		SELECT project_id FROM project_chats
+		WHERE chat_id = ? AND thread_id IN (?, 0)
+		ORDER BY thread_id DESC LIMIT 1
+	`, chatID, threadID).Scan(&projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(projectID)
+}
+
+// List returns all projects with their chats and components, ordered by name
+func (r *ProjectRepository) List() ([]*model.Project, error) {
+	rows, err := r.db.Query(`SELECT ` + projectColumns + ` FROM projects ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*model.Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		if err := r.loadDetails(p); err != nil {
+			return nil, err
+		}
+	}
+	return projects, nil
+}
+
+// loadDetails loads the chats and the component catalog of a project
+func (r *ProjectRepository) loadDetails(p *model.Project) error {
+	components, err := listComponents(r.db, p.ID)
+	if err != nil {
+		return err
+	}
+	p.Components = components
+
+	rows, err := r.db.Query(`SELECT chat_id, thread_id FROM project_chats WHERE project_id = ? ORDER BY chat_id, thread_id`, p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.Chats = nil
+	for rows.Next() {
+		var c model.ProjectChat
+		if err := rows.Scan(&c.ChatID, &c.ThreadID); err != nil {
+			return err
+		}
+		p.Chats = append(p.Chats, c)
+	}
+	return rows.Err()
+}
+
+func scanProject(row rowScanner) (*model.Project, error) {
+	p := &model.Project{}
+	err := row.Scan(&p.ID, &p.Name, &p.SystemPrompt, &p.RateLimitPerUser, &p.RateLimitGlobal,
+		&p.WebUsername, &p.WebPasswordHash, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
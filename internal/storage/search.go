@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// Search returns ideas matching the free-text query, most relevant first.
+// A non-zero projectID limits the search to the project's ideas.
+func (r *IdeaRepository) Search(text string, projectID int64, limit int) ([]*model.Idea, error) {
+	match := buildMatchQuery(text)
+	if match == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + ideaColumns + `
+		FROM ideas
+		JOIN (
+			SELECT rowid AS fts_id, bm25(ideas_fts) AS rank
+			FROM ideas_fts
+			WHERE ideas_fts MATCH ?
+				AND (? = 0 OR rowid IN (SELECT id FROM ideas WHERE project_id = ?))
+			ORDER BY rank
+			LIMIT ?
+		) f ON f.fts_id = ideas.id
+		WHERE ideas.merged_into_id = 0
+		ORDER BY f.rank
+	`
+
+	rows, err := r.db.Query(query, match, projectID, projectID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIdeas(rows)
+}
+
+// buildMatchQuery turns free text into an FTS5 query: every word of 3+ letters becomes
+// a prefix term and terms are OR-ed, so bm25 ranks ideas matching more words higher.
+// Long words are cut by two letters as a crude stemmer for inflected languages.
+func buildMatchQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, w := range words {
+		runes := []rune(w)
+		if len(runes) < 3 {
+			continue
+		}
+		if len(runes) > 5 {
+			runes = runes[:len(runes)-2]
+		}
+		term := string(runes)
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, `"`+term+`"*`)
+	}
+
+	return strings.Join(terms, " OR ")
+}
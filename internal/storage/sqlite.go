@@ -5,13 +5,24 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
 var db *sql.DB
 
-const schema = `
+// timeFormat matches the format of SQLite's CURRENT_TIMESTAMP, so values written
+// from Go and defaults written by SQLite compare correctly as text
+const timeFormat = "2006-01-02 15:04:05"
+
+// timestamp formats t for storage and comparison in SQLite
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+var schema = `
 CREATE TABLE IF NOT EXISTS ideas (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     telegram_message_id INTEGER NOT NULL,
@@ -37,8 +48,216 @@ CREATE INDEX IF NOT EXISTS idx_ideas_category ON ideas(category);
 CREATE INDEX IF NOT EXISTS idx_ideas_priority ON ideas(priority);
 CREATE INDEX IF NOT EXISTS idx_ideas_created_at ON ideas(created_at);
 CREATE INDEX IF NOT EXISTS idx_ideas_telegram_chat_id ON ideas(telegram_chat_id);
+
+CREATE TABLE IF NOT EXISTS idea_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL DEFAULT '',
+    to_status TEXT NOT NULL,
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_history_idea_id ON idea_status_history(idea_id);
+CREATE INDEX IF NOT EXISTS idx_status_history_changed_at ON idea_status_history(changed_at);
+
+CREATE TABLE IF NOT EXISTS themes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS idea_themes (
+    idea_id INTEGER PRIMARY KEY REFERENCES ideas(id) ON DELETE CASCADE,
+    theme_id INTEGER NOT NULL REFERENCES themes(id) ON DELETE CASCADE,
+    assigned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_themes_theme_id ON idea_themes(theme_id);
+
+CREATE TABLE IF NOT EXISTS enrichment_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    enriched_json TEXT NOT NULL,
+    model TEXT DEFAULT '',
+    prompt_hash TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (idea_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    file_name TEXT DEFAULT '',
+    content_type TEXT DEFAULT '',
+    size INTEGER DEFAULT 0,
+    storage_key TEXT NOT NULL,
+    telegram_file_id TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_idea_id ON attachments(idea_id);
+
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    system_prompt TEXT NOT NULL DEFAULT '',
+    rate_limit_per_user INTEGER NOT NULL DEFAULT 0,
+    rate_limit_global INTEGER NOT NULL DEFAULT 0,
+    web_username TEXT NOT NULL DEFAULT '',
+    web_password_hash TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_web_username ON projects(web_username) WHERE web_username != '';
+
+-- thread_id 0 binds the whole chat, other values bind a single forum topic
+CREATE TABLE IF NOT EXISTS project_chats (
+    chat_id INTEGER NOT NULL,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_chats_project_id ON project_chats(project_id);
+
+CREATE TABLE IF NOT EXISTS components (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    aliases TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_components_project_id ON components(project_id);
+
+-- Tag names are stored in lower case
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    color TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS idea_tags (
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (idea_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_tags_tag_id ON idea_tags(tag_id);
+
+-- A Telegram user votes for an idea once
+CREATE TABLE IF NOT EXISTS idea_votes (
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    telegram_user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idea_id, telegram_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_votes_created_at ON idea_votes(created_at);
+
+-- idea_id is the idea that was kept, merged_id the duplicate merged into it
+CREATE TABLE IF NOT EXISTS idea_merges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    idea_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    merged_id INTEGER NOT NULL,
+    raw_text TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    merged_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_merges_idea_id ON idea_merges(idea_id);
+
+-- For "blocks" from_id blocks to_id, for "parent" from_id is the epic of to_id
+CREATE TABLE IF NOT EXISTS idea_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    to_id INTEGER NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (from_id, to_id, type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_links_to_id ON idea_links(to_id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS ideas_fts USING fts5(title, body, tokenize = 'unicode61');
+
+CREATE TRIGGER IF NOT EXISTS ideas_fts_insert AFTER INSERT ON ideas BEGIN
+    INSERT INTO ideas_fts (rowid, title, body) VALUES (new.id, new.title, ` + ftsBody("new") + `);
+END;
+
+CREATE TRIGGER IF NOT EXISTS ideas_fts_update AFTER UPDATE ON ideas BEGIN
+    DELETE FROM ideas_fts WHERE rowid = old.id;
+    INSERT INTO ideas_fts (rowid, title, body) VALUES (new.id, new.title, ` + ftsBody("new") + `);
+END;
+
+CREATE TRIGGER IF NOT EXISTS ideas_fts_delete AFTER DELETE ON ideas BEGIN
+    DELETE FROM ideas_fts WHERE rowid = old.id;
+END;
+
+-- Backfill the search index for ideas created before it existed
+INSERT INTO ideas_fts (rowid, title, body)
+SELECT ideas.id, ideas.title, ` + ftsBody("ideas") + `
+FROM ideas WHERE ideas.id NOT IN (SELECT rowid FROM ideas_fts);
 `
 
+// migrations upgrade databases created by older versions of the schema.
+// The whole list is applied in order on every start, so each statement must be idempotent.
+// SQLite has no ADD COLUMN IF NOT EXISTS, so "duplicate column" errors are ignored.
+var migrations = []string{
+	`ALTER TABLE ideas ADD COLUMN clarifications TEXT DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN enrichment_version INTEGER NOT NULL DEFAULT 0`,
+	// Ideas enriched before versioning keep their analysis as version 1
+	`INSERT INTO enrichment_versions (idea_id, version, enriched_json)
+		SELECT id, 1, enriched_json FROM ideas
+		WHERE enriched_json != '' AND id NOT IN (SELECT idea_id FROM enrichment_versions)`,
+	`UPDATE ideas SET enrichment_version = 1 WHERE enrichment_version = 0 AND enriched_json != ''`,
+	`ALTER TABLE ideas ADD COLUMN overridden_fields TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN source TEXT NOT NULL DEFAULT 'command'`,
+	`ALTER TABLE ideas ADD COLUMN original_author_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN original_author_username TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN original_author_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN source_chat_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN source_message_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN source_url TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN message_thread_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN project_id INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_ideas_project_id ON ideas(project_id)`,
+	`ALTER TABLE ideas ADD COLUMN assignee TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS idx_ideas_assignee ON ideas(assignee COLLATE NOCASE)`,
+	`ALTER TABLE idea_status_history ADD COLUMN comment TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN reach INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN impact REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN confidence INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN effort REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE ideas ADD COLUMN score REAL NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_ideas_score ON ideas(score)`,
+	`ALTER TABLE ideas ADD COLUMN merged_into_id INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_ideas_merged_into_id ON ideas(merged_into_id)`,
+	// Set once an idea has been sent for clustering, whether it got a theme or not
+	`ALTER TABLE ideas ADD COLUMN clustered_at DATETIME`,
+	`ALTER TABLE idea_merges ADD COLUMN comments TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE ideas ADD COLUMN message_chat_id INTEGER NOT NULL DEFAULT 0`,
+}
+
+// ftsBody builds the SQL expression for the searchable text of an idea row:
+// the raw text plus the enriched summary, description and user story
+func ftsBody(row string) string {
+	field := func(path string) string {
+		return "COALESCE(json_extract(" + row + ".enriched_json, '$." + path + "'), '')"
+	}
+	return row + ".raw_text || ' ' || CASE WHEN json_valid(" + row + ".enriched_json) THEN " +
+		field("summary") + " || ' ' || " + field("detailed_description") + " || ' ' || " + field("user_story") +
+		" ELSE '' END"
+}
+
 // Init initializes the SQLite database
 func Init(dbPath string) error {
 	// Ensure directory exists
@@ -69,6 +288,11 @@ func Init(dbPath string) error {
 	if _, err := db.Exec(schema); err != nil {
 		return err
 	}
+	for _, m := range migrations {
+		if _, err := db.Exec(m); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return err
+		}
+	}
 
 	log.Printf("SQLite database initialized at %s", dbPath)
 	return nil
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// StatsRepository runs the aggregate queries of the analytics dashboard.
+// Merged duplicates are left out, and a non-zero projectID limits every query to a project.
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository() *StatsRepository {
+	return &StatsRepository{db: DB()}
+}
+
+// statsScope returns the conditions every dashboard query starts with
+func statsScope(alias string, projectID int64) (string, []interface{}) {
+	where := alias + "merged_into_id = 0"
+	if projectID == 0 {
+		return where, nil
+	}
+	return where + " AND " + alias + "project_id = ?", []interface{}{projectID}
+}
+
+// CountByWeek returns the number of ideas submitted since a time, keyed by the
+// Monday of each week as "2006-01-02". Weeks without ideas are missing.
+func (r *StatsRepository) CountByWeek(since time.Time, projectID int64) (map[string]int, error) {
+	where, args := statsScope("", projectID)
+	query := `
+		SELECT date(created_at, 'weekday 0', '-6 days') AS week, COUNT(*)
+		FROM ideas
+		WHERE ` + where + ` AND created_at >= ?
+		GROUP BY week
+	`
+	args = append(args, timestamp(since))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var week string
+		var count int
+		if err := rows.Scan(&week, &count); err != nil {
+			return nil, err
+		}
+		counts[week] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// CountByField returns the number of ideas for each value of status, category,
+// priority or complexity
+func (r *StatsRepository) CountByField(field string, projectID int64) (map[string]int, error) {
+	switch field {
+	case "status", "category", "priority", "complexity":
+	default:
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+
+	where, args := statsScope("", projectID)
+	query := `SELECT ` + field + `, COUNT(*) FROM ideas WHERE ` + where + ` GROUP BY ` + field
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, err
+		}
+		counts[value] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// TimesToStatus returns, for every idea that ever moved into one of the statuses,
+// the time from its creation to the first such move
+func (r *StatsRepository) TimesToStatus(statuses []model.IdeaStatus, projectID int64) ([]time.Duration, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	where, args := statsScope("i.", projectID)
+	placeholders := make([]string, len(statuses))
+	for i, s := range statuses {
+		placeholders[i] = "?"
+		args = append(args, string(s))
+	}
+	query := `
+		SELECT (julianday(MIN(h.changed_at)) - julianday(i.created_at)) * 86400
+		FROM idea_status_history h
+		JOIN ideas i ON i.id = h.idea_id
+		WHERE ` + where + ` AND h.to_status IN (` + strings.Join(placeholders, ",") + `)
+		GROUP BY h.idea_id
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var durations []time.Duration
+	for rows.Next() {
+		var seconds float64
+		if err := rows.Scan(&seconds); err != nil {
+			return nil, err
+		}
+		durations = append(durations, time.Duration(seconds*float64(time.Second)))
+	}
+
+	return durations, rows.Err()
+}
+
+// TopSubmitters returns the users who submitted the most ideas
+func (r *StatsRepository) TopSubmitters(limit int, projectID int64) ([]model.SubmitterCount, error) {
+	where, args := statsScope("", projectID)
+	query := `
+		SELECT CASE
+				WHEN MAX(telegram_username) != '' THEN '@' || MAX(telegram_username)
+				WHEN MAX(telegram_first_name) != '' THEN MAX(telegram_first_name)
+				ELSE 'id' || telegram_user_id
+			END,
+			COUNT(*) AS n
+		FROM ideas
+		WHERE ` + where + ` AND telegram_user_id != 0
+		GROUP BY telegram_user_id
+		ORDER BY n DESC, MIN(created_at) ASC
+		LIMIT ?
+	`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var submitters []model.SubmitterCount
+	for rows.Next() {
+		var s model.SubmitterCount
+		if err := rows.Scan(&s.Name, &s.Count); err != nil {
+			return nil, err
+		}
+		submitters = append(submitters, s)
+	}
+
+	return submitters, rows.Err()
+}
+
+// CountByChat returns the number of all, open and implemented ideas of every group,
+// largest first
+func (r *StatsRepository) CountByChat(open []model.IdeaStatus, implemented model.IdeaStatus, projectID int64) ([]model.GroupStat, error) {
+	where, scopeArgs := statsScope("", projectID)
+
+	openIn := "0"
+	var args []interface{}
+	if len(open) > 0 {
+		placeholders := make([]string, len(open))
+		for i, s := range open {
+			placeholders[i] = "?"
+			args = append(args, string(s))
+		}
+		openIn = "status IN (" + strings.Join(placeholders, ",") + ")"
+	}
+	args = append(args, string(implemented))
+	args = append(args, scopeArgs...)
+
+	query := `
+		SELECT telegram_chat_id, COUNT(*) AS n,
+			COALESCE(SUM(` + openIn + `), 0), COALESCE(SUM(status = ?), 0)
+		FROM ideas
+		WHERE ` + where + `
+		GROUP BY telegram_chat_id
+		ORDER BY n DESC
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []model.GroupStat
+	for rows.Next() {
+		var g model.GroupStat
+		if err := rows.Scan(&g.ChatID, &g.Total, &g.Open, &g.Implemented); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
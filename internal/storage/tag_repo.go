@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type TagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository() *TagRepository {
+	return &TagRepository{db: DB()}
+}
+
+// List returns all tags with the number of ideas tagged with each, by name
+func (r *TagRepository) List() ([]*model.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.color, t.created_at, COUNT(it.idea_id)
+		FROM tags t
+		LEFT JOIN idea_tags it ON it.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*model.Tag
+	for rows.Next() {
+		t := &model.Tag{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.Color, &t.CreatedAt, &t.IdeaCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// Create adds a tag
+func (r *TagRepository) Create(t *model.Tag) (int64, error) {
+	result, err := r.db.Exec(`INSERT INTO tags (name, color, created_at) VALUES (?, ?, ?)`,
+		t.Name, t.Color, timestamp(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Update saves the name and color of a tag
+func (r *TagRepository) Update(t *model.Tag) error {
+	_, err := r.db.Exec(`UPDATE tags SET name = ?, color = ? WHERE id = ?`, t.Name, t.Color, t.ID)
+	return err
+}
+
+// Delete removes a tag from all ideas and then the tag itself
+func (r *TagRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM idea_tags WHERE tag_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddToIdeas tags ideas in one transaction, creating the tag with the given color
+// when it doesn't exist. Ideas that already have the tag are left as they are.
+func (r *TagRepository) AddToIdeas(ideaIDs []int64, name, color string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name, color, created_at) VALUES (?, ?, ?)`,
+		name, color, timestamp(time.Now())); err != nil {
+		return err
+	}
+
+	var tagID int64
+	if err := tx.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&tagID); err != nil {
+		return err
+	}
+
+	now := timestamp(time.Now())
+	for _, id := range ideaIDs {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO idea_tags (idea_id, tag_id) VALUES (?, ?)`, id, tagID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE ideas SET updated_at = ? WHERE id = ?`, now, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RemoveFromIdea removes a tag from an idea by the tag's name
+func (r *TagRepository) RemoveFromIdea(ideaID int64, name string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM idea_tags WHERE idea_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)`
+	if _, err := tx.Exec(query, ideaID, name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE ideas SET updated_at = ? WHERE id = ?`, timestamp(time.Now()), ideaID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
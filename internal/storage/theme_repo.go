@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type ThemeRepository struct {
+	db *sql.DB
+}
+
+func NewThemeRepository() *ThemeRepository {
+	return &ThemeRepository{db: DB()}
+}
+
+// List returns all themes with the number of ideas in each, largest first
+func (r *ThemeRepository) List() ([]*model.Theme, error) {
+	query := `
+		SELECT t.id, t.name, t.description, t.created_at, t.updated_at, COUNT(it.idea_id)
+		FROM themes t
+		LEFT JOIN idea_themes it ON it.theme_id = t.id
+		GROUP BY t.id
+		ORDER BY COUNT(it.idea_id) DESC, t.name ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var themes []*model.Theme
+	for rows.Next() {
+		t := &model.Theme{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.CreatedAt, &t.UpdatedAt, &t.IdeaCount); err != nil {
+			return nil, err
+		}
+		themes = append(themes, t)
+	}
+
+	return themes, rows.Err()
+}
+
+// ListWithIdeas returns all themes together with their member ideas
+func (r *ThemeRepository) ListWithIdeas() ([]*model.Theme, error) {
+	themes, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*model.Theme, len(themes))
+	for _, t := range themes {
+		byID[t.ID] = t
+	}
+
+	query := `
+		SELECT it.theme_id, i.id, i.title, i.raw_text
+		FROM idea_themes it
+		JOIN ideas i ON i.id = it.idea_id
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var themeID int64
+		var s model.IdeaSummary
+		if err := rows.Scan(&themeID, &s.ID, &s.Title, &s.RawText); err != nil {
+			return nil, err
+		}
+		if t, ok := byID[themeID]; ok {
+			t.Ideas = append(t.Ideas, s)
+		}
+	}
+
+	return themes, rows.Err()
+}
+
+// Create inserts a new theme and returns its ID
+func (r *ThemeRepository) Create(name, description string) (int64, error) {
+	query := `INSERT INTO themes (name, description) VALUES (?, ?)`
+	result, err := r.db.Exec(query, name, description)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// AssignIdea puts an idea into a theme, replacing any previous assignment
+func (r *ThemeRepository) AssignIdea(ideaID, themeID int64) error {
+	now := timestamp(time.Now())
+
+	query := `INSERT OR REPLACE INTO idea_themes (idea_id, theme_id, assigned_at) VALUES (?, ?, ?)`
+	if _, err := r.db.Exec(query, ideaID, themeID, now); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(`UPDATE themes SET updated_at = ? WHERE id = ?`, now, themeID)
+	return err
+}
+
+// MarkClustered records that ideas have been sent for clustering, so that the ones
+// left without a theme are not sent again
+func (r *ThemeRepository) MarkClustered(ids []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := timestamp(time.Now())
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE ideas SET clustered_at = ? WHERE id = ?`, now, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListUnclustered returns open ideas that haven't been sent for clustering yet, oldest first
+func (r *ThemeRepository) ListUnclustered(limit int) ([]model.IdeaSummary, error) {
+	open, args := openStatusCondition("i.status")
+	query := `
+		SELECT i.id, i.title, i.raw_text
+		FROM ideas i
+		LEFT JOIN idea_themes it ON it.idea_id = i.id
+		WHERE it.idea_id IS NULL AND i.clustered_at IS NULL
+			AND ` + open + ` AND i.merged_into_id = 0
+		ORDER BY i.created_at ASC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []model.IdeaSummary
+	for rows.Next() {
+		var s model.IdeaSummary
+		if err := rows.Scan(&s.ID, &s.Title, &s.RawText); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// CountUnclustered returns the number of open ideas waiting to be clustered
+func (r *ThemeRepository) CountUnclustered() (int, error) {
+	open, args := openStatusCondition("i.status")
+	query := `
+		SELECT COUNT(*)
+		FROM ideas i
+		LEFT JOIN idea_themes it ON it.idea_id = i.id
+		WHERE it.idea_id IS NULL AND i.clustered_at IS NULL
+			AND ` + open + ` AND i.merged_into_id = 0
+	`
+
+	var count int
+	err := r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
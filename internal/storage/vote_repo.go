@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type VoteRepository struct {
+	db *sql.DB
+}
+
+func NewVoteRepository() *VoteRepository {
+	return &VoteRepository{db: DB()}
+}
+
+// Toggle adds the user's vote for an idea, or takes it back when they have voted already.
+// It reports whether the user votes for the idea now.
+func (r *VoteRepository) Toggle(ideaID, userID int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM idea_votes WHERE idea_id = ? AND telegram_user_id = ?`, ideaID, userID)
+	if err != nil {
+		return false, err
+	}
+	if removed, err := result.RowsAffected(); err != nil || removed > 0 {
+		return false, err
+	}
+
+	query := `INSERT INTO idea_votes (idea_id, telegram_user_id, created_at) VALUES (?, ?, ?)`
+	if _, err := r.db.Exec(query, ideaID, userID, timestamp(time.Now())); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TopVoted returns the ideas that got the most votes in the period [from, to), most voted first.
+// A non-zero chatID limits the list to ideas from that Telegram chat.
+func (r *VoteRepository) TopVoted(from, to time.Time, chatID int64, limit int) ([]model.VotedIdea, error) {
+	query := `
+		SELECT i.id, COALESCE(NULLIF(i.title, ''), i.raw_text), i.status, COUNT(*) AS votes
+		FROM idea_votes v
+		JOIN ideas i ON i.id = v.idea_id
+		WHERE v.created_at >= ? AND v.created_at < ? AND i.merged_into_id = 0
+	`
+	args := []interface{}{timestamp(from), timestamp(to)}
+
+	if chatID != 0 {
+		query += " AND i.telegram_chat_id = ?"
+		args = append(args, chatID)
+	}
+
+	query += " GROUP BY i.id ORDER BY votes DESC, i.id ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ideas []model.VotedIdea
+	for rows.Next() {
+		var v model.VotedIdea
+		if err := rows.Scan(&v.IdeaID, &v.IdeaTitle, &v.Status, &v.Votes); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, v)
+	}
+
+	return ideas, rows.Err()
+}
@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+)
+
+// mineLimit is the number of ideas listed by /mine
+const mineLimit = 20
+
+// chatIdea loads an idea a command refers to. Like the web UI, a chat only sees
+// the ideas of its project: a group those of the project it belongs to, a private
+// chat those of the projects of the user's groups. Administrators see every idea.
+func (b *Bot) chatIdea(msg *tgbotapi.Message, id int64) (*model.Idea, bool) {
+	idea, err := b.ideaService.GetByID(id)
+	if err != nil {
+		return nil, false
+	}
+	if b.admins[msg.From.ID] {
+		return idea, true
+	}
+
+	chats := []int64{msg.Chat.ID}
+	if msg.Chat.IsPrivate() {
+		if len(b.allowedGroups) == 0 {
+			return idea, true
+		}
+		chats = b.memberGroups(msg.From.ID)
+	}
+	for _, chatID := range chats {
+		project, err := b.ideaService.ProjectForChat(chatID, int64(b.threadOf(msg)))
+		if err != nil {
+			log.Printf("Failed to resolve project of chat %d: %v", chatID, err)
+			return nil, false
+		}
+		var projectID int64
+		if project != nil {
+			projectID = project.ID
+		}
+		if idea.ProjectID == projectID {
+			return idea, true
+		}
+	}
+	return nil, false
+}
+
+// handleAssignCommand handles "/assign <id> [@user]". Admins can assign anyone;
+// other users can take an unassigned idea or hand over an idea assigned to them.
+// "/assign <id> -" unassigns the idea.
+func (b *Bot) handleAssignCommand(msg *tgbotapi.Message) {
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) == 0 || len(fields) > 2 {
+		b.reply(msg, "❌ Укажите номер идеи и пользователя.\n\nПример: `/assign 42 @username`, `/assign 42` — взять себе, `/assign 42 -` — снять ответственного")
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "#"), 10, 64)
+	if err != nil {
+		b.reply(msg, "❌ Неверный номер идеи.")
+		return
+	}
+
+	self := msg.From.UserName
+	assignee := self
+	if len(fields) == 2 {
+		assignee = strings.TrimPrefix(fields[1], "@")
+		if assignee == "-" {
+			assignee = ""
+		}
+	}
+	if assignee == "" && len(fields) == 1 {
+		b.reply(msg, "❌ У вас нет имени пользователя в Telegram, назначить идею на вас нельзя.")
+		return
+	}
+
+	idea, ok := b.chatIdea(msg, id)
+	if !ok {
+		b.reply(msg, fmt.Sprintf("❌ Идея #%d не найдена.", id))
+		return
+	}
+	if idea.MergedIntoID != 0 {
+		b.reply(msg, fmt.Sprintf("❌ Идея #%d объединена с #%d, назначайте её.", id, idea.MergedIntoID))
+		return
+	}
+
+	if !b.admins[msg.From.ID] {
+		ownIdea := self != "" && strings.EqualFold(idea.Assignee, self)
+		takesFree := idea.Assignee == "" && self != "" && strings.EqualFold(assignee, self)
+		if !ownIdea && !takesFree {
+			b.reply(msg, "⛔ Переназначить идею может администратор или её ответственный.")
+			return
+		}
+	}
+
+	if err := b.ideaService.Assign(id, assignee); err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			b.reply(msg, "❌ "+validationErr.Message)
+			return
+		}
+		log.Printf("Error assigning idea %d: %v", id, err)
+		b.reply(msg, "❌ Не удалось назначить ответственного. Попробуйте позже.")
+		return
+	}
+
+	ideaURL := fmt.Sprintf("%s/ideas/%d", config.Get().Web.BaseURL, id)
+	if assignee == "" {
+		b.replyMarkdown(msg, fmt.Sprintf("👤 У [идеи \\#%d](%s) больше нет ответственного", id, escapeMarkdownV2(ideaURL)))
+		return
+	}
+	b.replyMarkdown(msg, fmt.Sprintf("👤 [Идея \\#%d](%s) назначена на %s", id, escapeMarkdownV2(ideaURL), escapeMarkdownV2("@"+assignee)))
+}
+
+// handleMineCommand lists open ideas assigned to the sender
+func (b *Bot) handleMineCommand(msg *tgbotapi.Message) {
+	if msg.From.UserName == "" {
+		b.reply(msg, "❌ У вас нет имени пользователя в Telegram, идеи назначаются по нему.")
+		return
+	}
+
+	ideas, err := b.ideaService.List(model.IdeaFilter{
+		Assignee: msg.From.UserName,
+		Status:   model.CurrentWorkflow().OpenStatuses(),
+		Limit:    mineLimit,
+	})
+	if err != nil {
+		log.Printf("Error listing ideas of @%s: %v", msg.From.UserName, err)
+		b.reply(msg, "❌ Не удалось получить список идей. Попробуйте позже.")
+		return
+	}
+
+	if len(ideas) == 0 {
+		b.reply(msg, "🎉 На вас нет открытых идей.")
+		return
+	}
+
+	cfg := config.Get()
+	response := "📋 *Ваши идеи:*\n\n"
+	for _, idea := range ideas {
+		title := idea.Title
+		if title == "" {
+			title = idea.RawText
+		}
+		if len([]rune(title)) > 60 {
+			title = string([]rune(title)[:60]) + "..."
+		}
+		ideaURL := fmt.Sprintf("%s/ideas/%d", cfg.Web.BaseURL, idea.ID)
+		response += fmt.Sprintf("• [\\#%d](%s) %s · _%s_\n", idea.ID, escapeMarkdownV2(ideaURL),
+			escapeMarkdownV2(title), escapeMarkdownV2(idea.Status.Label()))
+	}
+	b.replyMarkdown(msg, response)
+}
@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// captionCommand returns the command and its arguments when a photo or document
+// caption starts with a command, like "/idea@bot text". Message.Command only looks at the text.
+func captionCommand(msg *tgbotapi.Message) (string, string) {
+	for _, e := range msg.CaptionEntities {
+		if e.Type != "bot_command" || e.Offset != 0 || e.Length > len(msg.Caption) {
+			continue
+		}
+		// Commands are ASCII, so the UTF-16 length of the entity equals its length in bytes
+		command, _, _ := strings.Cut(msg.Caption[1:e.Length], "@")
+		return command, strings.TrimSpace(msg.Caption[e.Length:])
+	}
+	return "", ""
+}
+
+// messageFiles returns the photo or document of a message as attachments to download.
+// Only the Telegram file ID is set, the contents are fetched by downloadAttachments.
+func messageFiles(msg *tgbotapi.Message) []model.AttachmentInput {
+	var files []model.AttachmentInput
+
+	if len(msg.Photo) > 0 {
+		// Telegram sends several sizes of the same photo, the last one is the largest
+		photo := msg.Photo[len(msg.Photo)-1]
+		files = append(files, model.AttachmentInput{
+			Kind:           model.AttachmentPhoto,
+			FileName:       "photo.jpg",
+			ContentType:    "image/jpeg",
+			TelegramFileID: photo.FileID,
+		})
+	}
+
+	if doc := msg.Document; doc != nil {
+		contentType := doc.MimeType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(path.Ext(doc.FileName))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		files = append(files, model.AttachmentInput{
+			Kind:           model.AttachmentDocument,
+			FileName:       doc.FileName,
+			ContentType:    contentType,
+			TelegramFileID: doc.FileID,
+		})
+	}
+
+	return files
+}
+
+// downloadAttachments fetches the contents of the idea's files through the Bot API.
+// Files that are too large or fail to download are dropped, the idea is saved without them.
+func (b *Bot) downloadAttachments(ctx context.Context, input *model.CreateIdeaInput) {
+	maxSize := int64(config.Get().Attachments.MaxSizeMB) << 20
+
+	var downloaded []model.AttachmentInput
+	for _, att := range input.Attachments {
+		if att.Data == nil {
+			data, err := b.downloadFile(ctx, att.TelegramFileID, maxSize)
+			if err != nil {
+				log.Printf("Warning: skipped attachment %q: %v", att.FileName, err)
+				continue
+			}
+			att.Data = data
+		}
+		downloaded = append(downloaded, att)
+	}
+	input.Attachments = downloaded
+}
+
+func (b *Bot) downloadFile(ctx context.Context, fileID string, maxSize int64) ([]byte, error) {
+	file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if maxSize > 0 && int64(file.FileSize) > maxSize {
+		return nil, fmt.Errorf("file is %d bytes, limit is %d", file.FileSize, maxSize)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.Link(b.api.Token), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.api.Client.Do(req)
+	if err != nil {
+		// The error contains the URL with the bot token
+		return nil, fmt.Errorf("failed to download file %s", fileID)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file %s: %s", fileID, resp.Status)
+	}
+
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file %s", fileID)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxSize)
+	}
+	return data, nil
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -17,10 +20,28 @@ import (
 type Bot struct {
 	api           *tgbotapi.BotAPI
 	ideaService   *service.IdeaService
+	digestService *service.DigestService
 	allowedGroups map[int64]bool
+	admins        map[int64]bool
+	pool          *workerPool
+
+	conversations map[conversationKey]*conversation
+	convMu        sync.Mutex
+
+	members    map[int64]membership
+	chatTitles map[int64]string
+	membersMu  sync.Mutex
+
+	pending   map[string]*pendingIdea
+	pendingMu sync.Mutex
+
+	allowedTopics map[int64]map[int]bool
+	threads       map[messageRef]threadEntry
+	threadsSwept  time.Time
+	threadsMu     sync.Mutex
 }
 
-func NewBot(ideaService *service.IdeaService) (*Bot, error) {
+func NewBot(ideaService *service.IdeaService, digestService *service.DigestService) (*Bot, error) {
 	cfg := config.Get()
 
 	api, err := tgbotapi.NewBotAPI(cfg.Telegram.BotToken)
@@ -35,40 +56,91 @@ func NewBot(ideaService *service.IdeaService) (*Bot, error) {
 		allowedGroups[groupID] = true
 	}
 
+	admins := make(map[int64]bool)
+	for _, userID := range cfg.Telegram.Admins {
+		admins[userID] = true
+	}
+
+	allowedTopics := make(map[int64]map[int]bool)
+	for _, topic := range cfg.Telegram.AllowedTopics {
+		if allowedTopics[topic.ChatID] == nil {
+			allowedTopics[topic.ChatID] = make(map[int]bool)
+		}
+		allowedTopics[topic.ChatID][topic.ThreadID] = true
+	}
+
+	for topic, category := range cfg.Telegram.TopicCategories {
+		if !model.IdeaCategory(category).IsValid() {
+			return nil, fmt.Errorf("unknown category %q for topic %d:%d", category, topic.ChatID, topic.ThreadID)
+		}
+	}
+
 	log.Printf("Telegram bot authorized as @%s", api.Self.UserName)
 	log.Printf("Allowed groups: %v", cfg.Telegram.AllowedGroups)
 
-	return &Bot{
+	b := &Bot{
 		api:           api,
 		ideaService:   ideaService,
+		digestService: digestService,
 		allowedGroups: allowedGroups,
-	}, nil
+		admins:        admins,
+		conversations: make(map[conversationKey]*conversation),
+		members:       make(map[int64]membership),
+		chatTitles:    make(map[int64]string),
+		pending:       make(map[string]*pendingIdea),
+		allowedTopics: allowedTopics,
+		threads:       make(map[messageRef]threadEntry),
+	}
+	b.pool = newWorkerPool(cfg.Telegram.Workers, cfg.Telegram.QueueSize, b.handleUpdate)
+
+	return b, nil
 }
 
-// Start begins polling for updates
+// Drain waits for queued and in-flight updates after Start has returned
+func (b *Bot) Drain() {
+	b.pool.drain(config.Get().Telegram.DrainTimeout)
+}
+
+// WriteMetrics writes update queue metrics in the Prometheus text format
+func (b *Bot) WriteMetrics(w io.Writer) {
+	b.pool.writeMetrics(w)
+}
+
+// Start begins receiving updates, by long polling or through the webhook depending on TELEGRAM_MODE
 func (b *Bot) Start(ctx context.Context) error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	if config.Get().Telegram.Mode == ModeWebhook {
+		return b.startWebhook(ctx)
+	}
+
+	// getUpdates fails while a webhook is set, e.g. after switching back from webhook mode
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Failed to delete webhook: %v", err)
+	}
 
-	updates := b.api.GetUpdatesChan(u)
+	updates := b.pollUpdates(ctx)
 
 	log.Println("Telegram bot started, waiting for messages...")
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Telegram bot stopping...")
-			b.api.StopReceivingUpdates()
-			return ctx.Err()
-		case update := <-updates:
-			go b.handleUpdate(ctx, update)
+	// The channel is closed once polling has stopped after ctx is cancelled
+	for update := range updates {
+		// Blocks while the chat's queue is full, so polling slows down instead of piling up handlers
+		if err := b.pool.submit(ctx, update); err != nil {
+			log.Printf("Dropped update %d: %v", update.UpdateID, err)
 		}
 	}
+
+	log.Println("Telegram bot stopped")
+	return ctx.Err()
 }
 
 func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	log.Printf("Received update: %+v", update.UpdateID)
 
+	if update.CallbackQuery != nil {
+		b.handleCallback(ctx, update.CallbackQuery)
+		return
+	}
+
 	if update.Message == nil {
 		log.Printf("Update has no message, skipping")
 		return
@@ -76,67 +148,136 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 
 	log.Printf("Message from chat %d (%s): %s", update.Message.Chat.ID, update.Message.Chat.Title, update.Message.Text)
 
-	// Only process from allowed groups (if list is configured)
-	if len(b.allowedGroups) > 0 && !b.allowedGroups[update.Message.Chat.ID] {
+	// Private chats are open to members of the allowed groups, other chats must be allowed
+	if update.Message.Chat.IsPrivate() {
+		if !b.canUsePrivateChat(update.Message.From.ID) {
+			log.Printf("Ignored private message from user %d: not a member of allowed groups", update.Message.From.ID)
+			return
+		}
+		if isForwarded(update.Message) {
+			b.handleForward(ctx, update.Message)
+			return
+		}
+	} else if len(b.allowedGroups) > 0 && !b.allowedGroups[update.Message.Chat.ID] {
 		log.Printf("Ignored message from unauthorized chat: %d (%s)",
 			update.Message.Chat.ID, update.Message.Chat.Title)
 		return
+	} else if !b.topicAllowed(update.Message) {
+		log.Printf("Ignored message from topic %d of chat %d: not in allowed topics",
+			b.threadOf(update.Message), update.Message.Chat.ID)
+		return
 	}
 
-	// Only process /idea command
+	// Screenshots and files carry the command in their caption
+	if command, args := captionCommand(update.Message); command == "idea" {
+		b.handleIdeaCommand(ctx, update.Message, args)
+		return
+	}
+
+	// Replies may answer a clarifying question, other messages are ignored
 	if !update.Message.IsCommand() {
+		b.handleClarificationReply(ctx, update.Message)
 		return
 	}
 
 	switch update.Message.Command() {
 	case "idea":
-		b.handleIdeaCommand(ctx, update.Message)
+		b.handleIdeaCommand(ctx, update.Message, update.Message.CommandArguments())
+	case "ask":
+		b.handleAskCommand(ctx, update.Message)
+	case "skip":
+		b.handleSkipCommand(ctx, update.Message)
+	case "reanalyze":
+		b.handleReanalyzeCommand(ctx, update.Message)
+	case "assign":
+		b.handleAssignCommand(update.Message)
+	case "mine":
+		b.handleMineCommand(update.Message)
+	case "tag":
+		b.handleTagCommand(update.Message)
+	case "vote":
+		b.handleVoteCommand(update.Message)
 	case "start", "help":
 		b.handleHelpCommand(update.Message)
 	}
 }
 
-func (b *Bot) handleIdeaCommand(ctx context.Context, msg *tgbotapi.Message) {
-	ideaText := strings.TrimSpace(msg.CommandArguments())
+func (b *Bot) handleIdeaCommand(ctx context.Context, msg *tgbotapi.Message, args string) {
+	ideaText := strings.TrimSpace(args)
+
+	input := model.CreateIdeaInput{
+		TelegramMessageID: int64(msg.MessageID),
+		TelegramChatID:    msg.Chat.ID,
+		TelegramUserID:    msg.From.ID,
+		TelegramUsername:  msg.From.UserName,
+		TelegramFirstName: msg.From.FirstName,
+		Source:            model.SourceCommand,
+		MessageThreadID:   int64(b.threadOf(msg)),
+		Category:          b.topicCategory(msg),
+		Attachments:       messageFiles(msg),
+	}
+
+	// /idea as a reply captures the replied message, the command text becomes a comment
+	if replied := msg.ReplyToMessage; replied != nil && messageText(replied) != "" && !b.isOwnMessage(replied) {
+		captureReply(&input, msg.Chat, replied)
+		input.Attachments = append(input.Attachments, messageFiles(replied)...)
+		if ideaText != "" {
+			ideaText = messageText(replied) + "\n\nКомментарий: " + ideaText
+		} else {
+			ideaText = messageText(replied)
+		}
+	}
 
 	if ideaText == "" {
-		b.reply(msg, "❌ Пожалуйста, укажите текст идеи после команды.\n\nПример: `/idea добавить тёмную тему в консоль`")
+		b.reply(msg, "❌ Пожалуйста, укажите текст идеи после команды или ответьте командой /idea на сообщение с идеей.\n\nПример: `/idea добавить тёмную тему в консоль`")
 		return
 	}
 
-	if len(ideaText) < 10 {
+	input.RawText = ideaText
+	b.startIdea(ctx, msg, input)
+}
+
+// startIdea validates the idea text, then clarifies or submits the idea
+func (b *Bot) startIdea(ctx context.Context, msg *tgbotapi.Message, input model.CreateIdeaInput) {
+	if len(input.RawText) < 10 {
 		b.reply(msg, "❌ Идея слишком короткая. Опишите её подробнее (минимум 10 символов).")
 		return
 	}
 
-	if len(ideaText) > 2000 {
+	if len(input.RawText) > 2000 {
 		b.reply(msg, "❌ Идея слишком длинная (максимум 2000 символов).")
 		return
 	}
 
+	// Private submissions belong to one of the author's groups
+	if !b.chooseGroup(msg, &input) {
+		return
+	}
+
+	b.processIdea(ctx, msg, input)
+}
+
+// processIdea clarifies or submits a validated idea, replying to msg
+func (b *Bot) processIdea(ctx context.Context, msg *tgbotapi.Message, input model.CreateIdeaInput) {
 	// Send "thinking" message
 	thinkingMsg := b.reply(msg, "🤔 Анализирую идею...")
 
-	// Get username
-	username := msg.From.UserName
-	if username == "" {
-		username = msg.From.FirstName
+	// Vague ideas are clarified with the author first, the dialogue submits the idea when done
+	if b.startClarification(ctx, msg, thinkingMsg, input) {
+		return
 	}
 
-	// Create and enrich the idea
-	input := model.CreateIdeaInput{
-		TelegramMessageID: int64(msg.MessageID),
-		TelegramChatID:    msg.Chat.ID,
-		TelegramUserID:    msg.From.ID,
-		TelegramUsername:  msg.From.UserName,
-		TelegramFirstName: msg.From.FirstName,
-		RawText:           ideaText,
-	}
+	b.submitIdea(ctx, thinkingMsg, input)
+}
 
+// submitIdea creates and enriches the idea and edits the status message with the result
+func (b *Bot) submitIdea(ctx context.Context, statusMsg *tgbotapi.Message, input model.CreateIdeaInput) {
 	// Use timeout context for Claude API
 	enrichCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
+	b.downloadAttachments(enrichCtx, &input)
+
 	idea, enriched, err := b.ideaService.CreateAndEnrich(enrichCtx, input)
 	if err != nil {
 		cfg := config.Get()
@@ -151,15 +292,15 @@ func (b *Bot) handleIdeaCommand(ctx context.Context, msg *tgbotapi.Message) {
 				escapeMarkdownV2(dupErr.Reason),
 				dupErr.SimilarID,
 				escapeMarkdownV2(existingURL))
-			b.editMessageMarkdown(thinkingMsg, response)
+			b.editMessageMarkdown(statusMsg, response)
 			return
 		}
 
 		if strings.Contains(err.Error(), "rate limit") {
-			b.editMessage(thinkingMsg, "⚠️ Слишком много идей за последний час. Попробуйте позже.")
+			b.editMessage(statusMsg, "⚠️ Слишком много идей за последний час. Попробуйте позже.")
 		} else {
 			log.Printf("Error creating idea: %v", err)
-			b.editMessage(thinkingMsg, "❌ Произошла ошибка при сохранении идеи. Попробуйте позже.")
+			b.editMessage(statusMsg, "❌ Произошла ошибка при сохранении идеи. Попробуйте позже.")
 		}
 		return
 	}
@@ -175,15 +316,100 @@ func (b *Bot) handleIdeaCommand(ctx context.Context, msg *tgbotapi.Message) {
 		log.Printf("Formatting enriched response for idea %d", idea.ID)
 		response = service.FormatEnrichedForTelegram(enriched)
 		response += fmt.Sprintf("\n\n💾 [Идея \\#%d](%s) сохранена", idea.ID, escapeMarkdownV2(ideaURL))
+		if idea.Assignee != "" {
+			response += "\n👤 Ответственный: " + escapeMarkdownV2("@"+idea.Assignee)
+		}
 		log.Printf("Formatted response length: %d chars", len(response))
 	} else {
 		response = fmt.Sprintf("💾 [Идея \\#%d](%s) сохранена\\!\n\n📝 %s\n\n_\\(Автоматический анализ недоступен\\)_",
-			idea.ID, escapeMarkdownV2(ideaURL), escapeMarkdownV2(ideaText))
+			idea.ID, escapeMarkdownV2(ideaURL), escapeMarkdownV2(input.RawText))
 	}
+	response += formatCredit(input)
 
 	log.Printf("Sending edited message for idea %d", idea.ID)
-	b.editMessageMarkdown(thinkingMsg, response)
+	b.editMessageMarkdown(statusMsg, response)
 	log.Printf("Edit message sent for idea %d", idea.ID)
+
+	// Ideas submitted privately are announced in their group
+	if statusMsg != nil && statusMsg.Chat.ID != input.TelegramChatID {
+		b.announce(idea, input)
+	}
+}
+
+func (b *Bot) handleAskCommand(ctx context.Context, msg *tgbotapi.Message) {
+	question := strings.TrimSpace(msg.CommandArguments())
+	if question == "" {
+		b.reply(msg, "❌ Укажите вопрос после команды.\n\nПример: `/ask предлагал ли кто-нибудь тёмную тему?`")
+		return
+	}
+
+	projectID, ok := b.askScope(msg)
+	if !ok {
+		b.reply(msg, "❌ Ваши группы относятся к разным проектам. Задайте вопрос в группе нужного проекта.")
+		return
+	}
+
+	thinkingMsg := b.reply(msg, "🔎 Ищу по базе идей...")
+
+	askCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	answer, err := b.ideaService.Ask(askCtx, question, projectID)
+	if err != nil {
+		log.Printf("Error answering question: %v", err)
+		b.editMessage(thinkingMsg, "❌ Не удалось получить ответ. Попробуйте позже.")
+		return
+	}
+
+	cfg := config.Get()
+	response := "💬 " + escapeMarkdownV2(answer.Text)
+	if len(answer.Sources) > 0 {
+		response += "\n\n📎 *Источники:*\n"
+		for _, idea := range answer.Sources {
+			title := idea.Title
+			if title == "" {
+				title = idea.RawText
+			}
+			if len([]rune(title)) > 60 {
+				title = string([]rune(title)[:60]) + "..."
+			}
+			ideaURL := fmt.Sprintf("%s/ideas/%d", cfg.Web.BaseURL, idea.ID)
+			response += fmt.Sprintf("• [\\#%d](%s) %s\n", idea.ID, escapeMarkdownV2(ideaURL), escapeMarkdownV2(title))
+		}
+	}
+
+	b.editMessageMarkdown(thinkingMsg, response)
+}
+
+func (b *Bot) handleReanalyzeCommand(ctx context.Context, msg *tgbotapi.Message) {
+	if !b.admins[msg.From.ID] {
+		b.reply(msg, "⛔ Команда доступна только администраторам.")
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(msg.CommandArguments()), "#"), 10, 64)
+	if err != nil {
+		b.reply(msg, "❌ Укажите номер идеи.\n\nПример: `/reanalyze 42`")
+		return
+	}
+
+	thinkingMsg := b.reply(msg, fmt.Sprintf("🤔 Анализирую идею #%d заново...", id))
+
+	enrichCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	idea, err := b.ideaService.Reenrich(enrichCtx, id)
+	if err != nil {
+		log.Printf("Error re-enriching idea %d: %v", id, err)
+		b.editMessage(thinkingMsg, fmt.Sprintf("❌ Не удалось переанализировать идею #%d.", id))
+		return
+	}
+
+	cfg := config.Get()
+	ideaURL := fmt.Sprintf("%s/ideas/%d", cfg.Web.BaseURL, idea.ID)
+	response := service.FormatEnrichedForTelegram(idea.Enriched)
+	response += fmt.Sprintf("\n\n🔁 [Идея \\#%d](%s) переанализирована, версия %d", idea.ID, escapeMarkdownV2(ideaURL), idea.EnrichmentVersion)
+	b.editMessageMarkdown(thinkingMsg, response)
 }
 
 func (b *Bot) handleHelpCommand(msg *tgbotapi.Message) {
@@ -193,11 +419,22 @@ Bot for collecting and analyzing feature ideas with AI\.
 
 *Commands:*
 /idea <text> \- Submit a new idea
+/idea \(as a reply\) \- Submit the replied message as an idea, crediting its author
+/idea \(as a photo or file caption\) \- Submit an idea with a screenshot or file attached
+/ask <question> \- Ask about existing ideas
+/skip \- Submit the idea without answering clarifying questions
+/reanalyze <id> \- Re-run the AI analysis of an idea \(admins only\)
+/assign <id> \[@user\] \- Assign an idea to a user, or take it yourself
+/mine \- List open ideas assigned to you
+/tag <id> <tag> \[\-tag\] \- Add tags to an idea, or remove the ones prefixed with \-
+/vote <id> \- Vote for an idea, again to take the vote back
 /help \- Show this help
 
 *Example:*
 \` + "`" + `/idea Add Slack integration for build notifications\` + "`" + `
 
+You can also message the bot privately: send /idea or forward a message to submit it as an idea for one of your groups\.
+
 Your idea will be analyzed by AI and saved for review\.`
 
 	b.replyMarkdown(msg, help)
@@ -207,7 +444,7 @@ func (b *Bot) reply(msg *tgbotapi.Message, text string) *tgbotapi.Message {
 	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
 	reply.ReplyToMessageID = msg.MessageID
 
-	sent, err := b.api.Send(reply)
+	sent, err := b.send(reply, b.threadOf(msg))
 	if err != nil {
 		log.Printf("Failed to send message: %v", err)
 		return nil
@@ -220,7 +457,7 @@ func (b *Bot) replyMarkdown(msg *tgbotapi.Message, text string) *tgbotapi.Messag
 	reply.ReplyToMessageID = msg.MessageID
 	reply.ParseMode = tgbotapi.ModeMarkdownV2
 
-	sent, err := b.api.Send(reply)
+	sent, err := b.send(reply, b.threadOf(msg))
 	if err != nil {
 		log.Printf("Failed to send markdown message: %v", err)
 		// Fallback to plain text
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// handleForward accepts a message forwarded to the bot in a private chat as an idea
+func (b *Bot) handleForward(ctx context.Context, msg *tgbotapi.Message) {
+	text := strings.TrimSpace(messageText(msg))
+	if text == "" {
+		b.reply(msg, "❌ В пересланном сообщении нет текста.")
+		return
+	}
+
+	input := model.CreateIdeaInput{
+		TelegramMessageID: int64(msg.MessageID),
+		TelegramChatID:    msg.Chat.ID,
+		TelegramUserID:    msg.From.ID,
+		TelegramUsername:  msg.From.UserName,
+		TelegramFirstName: msg.From.FirstName,
+		RawText:           text,
+		Source:            model.SourceForward,
+		Attachments:       messageFiles(msg),
+	}
+
+	switch {
+	case msg.ForwardFrom != nil:
+		input.OriginalAuthor = userAuthor(msg.ForwardFrom)
+	case msg.ForwardFromChat != nil:
+		input.OriginalAuthor = chatAuthor(msg.ForwardFromChat, msg.ForwardSignature)
+		if msg.ForwardFromMessageID != 0 {
+			input.SourceChatID = msg.ForwardFromChat.ID
+			input.SourceMessageID = int64(msg.ForwardFromMessageID)
+			input.SourceURL = messageLink(msg.ForwardFromChat, msg.ForwardFromMessageID)
+		}
+	case msg.ForwardSenderName != "":
+		// The author hides their account in forwards, only the name is known
+		input.OriginalAuthor = model.OriginalAuthor{Name: msg.ForwardSenderName}
+	}
+
+	b.startIdea(ctx, msg, input)
+}
+
+// captureReply records the replied message as the source of an idea
+func captureReply(input *model.CreateIdeaInput, chat *tgbotapi.Chat, replied *tgbotapi.Message) {
+	input.Source = model.SourceReply
+	input.SourceChatID = chat.ID
+	input.SourceMessageID = int64(replied.MessageID)
+	input.SourceURL = messageLink(chat, replied.MessageID)
+
+	switch {
+	case replied.SenderChat != nil:
+		// Anonymous admins and linked channels post on behalf of a chat
+		input.OriginalAuthor = chatAuthor(replied.SenderChat, replied.AuthorSignature)
+	case replied.From != nil:
+		input.OriginalAuthor = userAuthor(replied.From)
+	}
+}
+
+// isOwnMessage reports whether the message was sent by this bot
+func (b *Bot) isOwnMessage(msg *tgbotapi.Message) bool {
+	return msg.From != nil && msg.From.ID == b.api.Self.ID
+}
+
+func isForwarded(msg *tgbotapi.Message) bool {
+	return msg.ForwardDate != 0
+}
+
+// messageText returns the text of a message, or the caption of a media message
+func messageText(msg *tgbotapi.Message) string {
+	if msg.Text != "" {
+		return msg.Text
+	}
+	return msg.Caption
+}
+
+// messageLink builds a t.me link to a message. Only public chats and supergroups
+// have message links, for other chats it returns an empty string.
+func messageLink(chat *tgbotapi.Chat, messageID int) string {
+	if chat.UserName != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chat.UserName, messageID)
+	}
+	// Supergroup and channel IDs are -100 followed by the internal ID used in t.me/c links
+	id := strconv.FormatInt(chat.ID, 10)
+	if strings.HasPrefix(id, "-100") {
+		return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), messageID)
+	}
+	return ""
+}
+
+func userAuthor(user *tgbotapi.User) model.OriginalAuthor {
+	return model.OriginalAuthor{
+		TelegramUserID:   user.ID,
+		TelegramUsername: user.UserName,
+		Name:             strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}
+}
+
+func chatAuthor(chat *tgbotapi.Chat, signature string) model.OriginalAuthor {
+	name := chat.Title
+	if signature != "" {
+		name += " (" + signature + ")"
+	}
+	return model.OriginalAuthor{TelegramUsername: chat.UserName, Name: name}
+}
+
+// formatCredit returns the MarkdownV2 line crediting the original author of a captured idea
+func formatCredit(input model.CreateIdeaInput) string {
+	if !input.OriginalAuthor.IsSet() && input.SourceURL == "" {
+		return ""
+	}
+
+	credit := "\n👤 Автор идеи: "
+	if input.OriginalAuthor.IsSet() {
+		credit += escapeMarkdownV2(input.OriginalAuthor.String())
+	} else {
+		credit += "неизвестен"
+	}
+	if input.SourceURL != "" {
+		credit += fmt.Sprintf(" · [исходное сообщение](%s)", escapeMarkdownV2(input.SourceURL))
+	}
+	return credit
+}
@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// conversationKey identifies a clarification dialogue: one per user per chat
+type conversationKey struct {
+	chatID int64
+	userID int64
+}
+
+// conversation is a clarification dialogue with the author of a vague idea.
+// It moves from one question to the next as the author replies, and ends when
+// all questions are answered, the author sends /skip or the timeout expires.
+type conversation struct {
+	ideaMsg       *tgbotapi.Message
+	input         model.CreateIdeaInput
+	questions     []string
+	answers       []model.Clarification
+	questionMsgID int
+	timer         *time.Timer
+}
+
+// Status messages shown when a dialogue ends and its idea is submitted
+const (
+	statusAnswered = "🤔 Анализирую идею с учётом уточнений..."
+	statusTimedOut = "⏱ Время на ответы вышло, анализирую идею..."
+	statusReplaced = "📨 Вы отправили новую идею, анализирую эту без дальнейших уточнений..."
+)
+
+// current returns the question the author is expected to answer
+func (c *conversation) current() string {
+	return c.questions[len(c.answers)]
+}
+
+// startClarification asks the model whether the idea is too vague and, if so, starts a
+// dialogue with the author. It returns false when the idea should be submitted right away.
+func (b *Bot) startClarification(ctx context.Context, msg *tgbotapi.Message, statusMsg *tgbotapi.Message, input model.CreateIdeaInput) bool {
+	cfg := config.Get()
+	if !cfg.Clarify.Enabled || statusMsg == nil {
+		return false
+	}
+
+	clarifyCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	questions, err := b.ideaService.ClarifyingQuestions(clarifyCtx, input.RawText)
+	if err != nil {
+		log.Printf("Warning: failed to get clarifying questions: %v", err)
+		return false
+	}
+	if len(questions) == 0 {
+		return false
+	}
+
+	key := conversationKey{chatID: msg.Chat.ID, userID: msg.From.ID}
+	conv := &conversation{
+		ideaMsg:       msg,
+		input:         input,
+		questions:     questions,
+		questionMsgID: statusMsg.MessageID,
+	}
+
+	// A new dialogue replaces an unfinished one, whose idea is submitted as it is, like with /skip
+	b.convMu.Lock()
+	prev := b.conversations[key]
+	b.convMu.Unlock()
+	if prev != nil {
+		b.finishClarification(ctx, key, prev, statusReplaced)
+	}
+
+	b.convMu.Lock()
+	conv.timer = time.AfterFunc(cfg.Clarify.Timeout, func() {
+		// The timeout is handled on the chat's worker like the author's replies,
+		// so it is drained on shutdown and can't race with them
+		err := b.pool.run(context.Background(), key.chatID, func(ctx context.Context) {
+			b.finishClarification(ctx, key, conv, statusTimedOut)
+		})
+		if err != nil {
+			log.Printf("Failed to queue timed out clarification for user %d in chat %d: %v", key.userID, key.chatID, err)
+		}
+	})
+	b.conversations[key] = conv
+	b.convMu.Unlock()
+
+	log.Printf("Started clarification for user %d in chat %d: %d questions", key.userID, key.chatID, len(questions))
+	b.editMessage(statusMsg, formatQuestion(conv))
+	return true
+}
+
+// handleClarificationReply records the author's reply to the current question.
+// It returns false if the message is not part of a clarification dialogue.
+func (b *Bot) handleClarificationReply(ctx context.Context, msg *tgbotapi.Message) bool {
+	if msg.ReplyToMessage == nil || msg.From == nil {
+		return false
+	}
+	answer := strings.TrimSpace(msg.Text)
+	if answer == "" {
+		return false
+	}
+
+	key := conversationKey{chatID: msg.Chat.ID, userID: msg.From.ID}
+
+	b.convMu.Lock()
+	conv, ok := b.conversations[key]
+	if !ok || msg.ReplyToMessage.MessageID != conv.questionMsgID {
+		b.convMu.Unlock()
+		return false
+	}
+
+	conv.answers = append(conv.answers, model.Clarification{Question: conv.current(), Answer: answer})
+	done := len(conv.answers) == len(conv.questions)
+	if !done {
+		conv.timer.Reset(config.Get().Clarify.Timeout)
+	}
+	b.convMu.Unlock()
+
+	if done {
+		b.finishClarification(ctx, key, conv, statusAnswered)
+		return true
+	}
+
+	sent := b.reply(msg, formatQuestion(conv))
+	if sent != nil {
+		b.convMu.Lock()
+		conv.questionMsgID = sent.MessageID
+		b.convMu.Unlock()
+	}
+	return true
+}
+
+// handleSkipCommand ends the author's dialogue early and submits the idea with the answers so far
+func (b *Bot) handleSkipCommand(ctx context.Context, msg *tgbotapi.Message) {
+	key := conversationKey{chatID: msg.Chat.ID, userID: msg.From.ID}
+
+	b.convMu.Lock()
+	conv, ok := b.conversations[key]
+	b.convMu.Unlock()
+
+	if !ok {
+		b.reply(msg, "Нет активных уточнений.")
+		return
+	}
+	b.finishClarification(ctx, key, conv, statusAnswered)
+}
+
+// finishClarification ends the dialogue and submits the idea with the collected answers,
+// replying to the idea with the status. Only the first call for a dialogue submits it.
+func (b *Bot) finishClarification(ctx context.Context, key conversationKey, conv *conversation, status string) {
+	b.convMu.Lock()
+	if b.conversations[key] != conv {
+		b.convMu.Unlock()
+		return
+	}
+	delete(b.conversations, key)
+	conv.timer.Stop()
+	input := conv.input
+	input.Clarifications = conv.answers
+	b.convMu.Unlock()
+
+	log.Printf("Clarification for user %d in chat %d finished with %d of %d answers", key.userID, key.chatID, len(input.Clarifications), len(conv.questions))
+
+	statusMsg := b.reply(conv.ideaMsg, status)
+	b.submitIdea(ctx, statusMsg, input)
+}
+
+func formatQuestion(conv *conversation) string {
+	return fmt.Sprintf("❓ Уточняющий вопрос %d/%d:\n\n%s\n\nОтветьте реплаем на это сообщение. /skip — отправить идею без дальнейших уточнений.",
+		len(conv.answers)+1, len(conv.questions), conv.current())
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+	"github.com/josinSbazin/idea-bot/internal/scheduler"
+)
+
+// RunDigests posts a weekly digest to every allowed group on its schedule.
+// Groups use TELEGRAM_DIGEST_SCHEDULE unless they have an entry in TELEGRAM_DIGEST_SCHEDULES.
+func (b *Bot) RunDigests(ctx context.Context) {
+	cfg := config.Get()
+
+	for _, groupID := range cfg.Telegram.AllowedGroups {
+		spec := cfg.Telegram.DigestSchedule
+		if groupSpec, ok := cfg.Telegram.DigestSchedules[groupID]; ok {
+			spec = groupSpec
+		}
+		if spec == "" {
+			continue
+		}
+
+		schedule, err := scheduler.Parse(spec)
+		if err != nil {
+			log.Printf("Warning: invalid digest schedule for group %d: %v", groupID, err)
+			continue
+		}
+
+		chatID := groupID
+		go scheduler.Run(ctx, fmt.Sprintf("telegram digest %d", chatID), schedule, func(ctx context.Context) {
+			if err := b.postDigest(chatID, schedule.Location); err != nil {
+				log.Printf("Failed to post digest to group %d: %v", chatID, err)
+			}
+		})
+	}
+}
+
+// postDigest builds the weekly digest for a group and posts it there,
+// with dates in the timezone of the group's schedule
+func (b *Bot) postDigest(chatID int64, loc *time.Location) error {
+	digest, err := b.digestService.BuildLatest(chatID)
+	if err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, service.FormatDigestForTelegram(digest, loc))
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.DisableWebPagePreview = true
+
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Failed to send markdown digest: %v, trying plain text", err)
+		msg.Text = stripMarkdown(msg.Text)
+		msg.ParseMode = ""
+		if _, err := b.api.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Digest posted to group %d", chatID)
+	return nil
+}
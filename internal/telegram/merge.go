@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// NotifyMerged tells the authors of both ideas that a duplicate was merged, replying
+// to the messages the ideas were submitted with. An author of both ideas is told once.
+// The messages are sent from the worker of the duplicate's chat, so the caller doesn't
+// wait for Telegram and the notification is drained on shutdown.
+func (b *Bot) NotifyMerged(idea, into *model.Idea) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := b.pool.run(ctx, idea.TelegramChatID, func(context.Context) {
+		b.notifyMerged(idea, into)
+	})
+	if err != nil {
+		log.Printf("Failed to queue merge notification for idea %d: %v", idea.ID, err)
+	}
+}
+
+func (b *Bot) notifyMerged(idea, into *model.Idea) {
+	baseURL := config.Get().Web.BaseURL
+	intoLink := fmt.Sprintf("[идеей \\#%d](%s)", into.ID, escapeMarkdownV2(fmt.Sprintf("%s/ideas/%d", baseURL, into.ID)))
+
+	title := into.Title
+	if title == "" {
+		title = into.RawText
+	}
+	if len([]rune(title)) > 100 {
+		title = string([]rune(title)[:100]) + "..."
+	}
+
+	b.notifyAuthor(idea, fmt.Sprintf("🔗 %s, ваша идея \\#%d объединена с похожей %s «%s», обсуждение продолжится там",
+		mentionAuthor(idea), idea.ID, intoLink, escapeMarkdownV2(title)))
+
+	if into.TelegramUserID != idea.TelegramUserID {
+		b.notifyAuthor(into, fmt.Sprintf("🔗 %s, с вашей %s объединена похожая идея \\#%d от %s",
+			mentionAuthor(into), intoLink, idea.ID, escapeMarkdownV2(authorName(idea))))
+	}
+}
+
+// notifyAuthor replies to the message an idea was submitted with, in the private
+// chat for ideas submitted from there
+func (b *Bot) notifyAuthor(idea *model.Idea, text string) {
+	chatID, threadID := idea.TelegramChatID, int(idea.MessageThreadID)
+	if idea.MessageChatID != 0 {
+		chatID, threadID = idea.MessageChatID, 0
+	}
+	if chatID == 0 {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyToMessageID = int(idea.TelegramMessageID)
+	msg.AllowSendingWithoutReply = true
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.DisableWebPagePreview = true
+	if _, err := b.send(msg, threadID); err != nil {
+		log.Printf("Failed to notify the author of idea %d in chat %d: %v", idea.ID, chatID, err)
+	}
+}
+
+// mentionAuthor mentions the author of an idea in MarkdownV2, by username when they have one
+func mentionAuthor(idea *model.Idea) string {
+	if idea.TelegramUsername != "" {
+		return escapeMarkdownV2("@" + idea.TelegramUsername)
+	}
+	return fmt.Sprintf("[%s](tg://user?id=%d)", escapeMarkdownV2(idea.TelegramFirstName), idea.TelegramUserID)
+}
+
+// authorName returns the username or the first name of an idea's author
+func authorName(idea *model.Idea) string {
+	if idea.TelegramUsername != "" {
+		return "@" + idea.TelegramUsername
+	}
+	return idea.TelegramFirstName
+}
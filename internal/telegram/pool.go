@@ -0,0 +1,205 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// errPoolClosed is returned by submit after draining has started
+var errPoolClosed = errors.New("worker pool is closed")
+
+// task is a unit of work queued on a worker
+type task func(ctx context.Context)
+
+// workerPool handles updates with a fixed number of workers. Updates are sharded
+// by chat ID, so messages from one chat are handled in order while chats of different
+// workers run in parallel. A worker serves many chats from one queue: a slow update
+// delays the other chats of its worker, and submit blocks when that queue is full.
+type workerPool struct {
+	queues  []chan task
+	handler func(ctx context.Context, update tgbotapi.Update)
+
+	// ctx is passed to handlers; it is only cancelled when draining times out
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	inFlight  atomic.Int64
+	processed atomic.Int64
+	rejected  atomic.Int64
+}
+
+func newWorkerPool(workers, queueSize int, handler func(ctx context.Context, update tgbotapi.Update)) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &workerPool{
+		queues:  make([]chan task, workers),
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan task, queueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+func (p *workerPool) work(queue chan task) {
+	defer p.wg.Done()
+	for t := range queue {
+		p.inFlight.Add(1)
+		p.handle(t)
+		p.inFlight.Add(-1)
+		p.processed.Add(1)
+	}
+}
+
+// handle runs a task, a panic must not kill the worker and stall its chats
+func (p *workerPool) handle(t task) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("Panic in telegram worker: %v", err)
+		}
+	}()
+	t(p.ctx)
+}
+
+// submit queues an update, blocking while the chat's worker queue is full.
+// It gives up when ctx is done, which lets callers apply backpressure upstream.
+func (p *workerPool) submit(ctx context.Context, update tgbotapi.Update) error {
+	return p.run(ctx, updateChatID(update), func(ctx context.Context) {
+		p.handler(ctx, update)
+	})
+}
+
+// run queues work for a chat that doesn't come from an update, e.g. a timer.
+// It runs on the chat's worker after the updates queued before it.
+func (p *workerPool) run(ctx context.Context, chatID int64, t task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.rejected.Add(1)
+		return errPoolClosed
+	}
+
+	queue := p.queues[shard(chatID, len(p.queues))]
+	select {
+	case queue <- t:
+		return nil
+	case <-ctx.Done():
+		p.rejected.Add(1)
+		return ctx.Err()
+	}
+}
+
+// drain stops accepting updates and waits for queued and in-flight handlers.
+// When the timeout passes, handlers' context is cancelled and drain waits for them to return.
+func (p *workerPool) drain(timeout time.Duration) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("Telegram worker pool drained")
+	case <-time.After(timeout):
+		log.Printf("Telegram worker pool drain timed out after %s, cancelling %d handlers", timeout, p.inFlight.Load())
+		p.cancel()
+		<-done
+	}
+	p.cancel()
+}
+
+// queueDepth returns the number of queued updates for each worker
+func (p *workerPool) queueDepth() []int {
+	depth := make([]int, len(p.queues))
+	for i, queue := range p.queues {
+		depth[i] = len(queue)
+	}
+	return depth
+}
+
+// writeMetrics writes pool metrics in the Prometheus text format
+func (p *workerPool) writeMetrics(w io.Writer) {
+	total := 0
+	fmt.Fprintln(w, "# HELP telegram_queue_depth Updates waiting in a worker queue.")
+	fmt.Fprintln(w, "# TYPE telegram_queue_depth gauge")
+	for i, depth := range p.queueDepth() {
+		fmt.Fprintf(w, "telegram_queue_depth{worker=\"%d\"} %d\n", i, depth)
+		total += depth
+	}
+
+	fmt.Fprintln(w, "# HELP telegram_queue_depth_total Updates waiting in all worker queues.")
+	fmt.Fprintln(w, "# TYPE telegram_queue_depth_total gauge")
+	fmt.Fprintf(w, "telegram_queue_depth_total %d\n", total)
+
+	fmt.Fprintln(w, "# HELP telegram_queue_capacity Capacity of each worker queue.")
+	fmt.Fprintln(w, "# TYPE telegram_queue_capacity gauge")
+	fmt.Fprintf(w, "telegram_queue_capacity %d\n", cap(p.queues[0]))
+
+	fmt.Fprintln(w, "# HELP telegram_workers Number of update workers.")
+	fmt.Fprintln(w, "# TYPE telegram_workers gauge")
+	fmt.Fprintf(w, "telegram_workers %d\n", len(p.queues))
+
+	fmt.Fprintln(w, "# HELP telegram_handlers_in_flight Updates being handled right now.")
+	fmt.Fprintln(w, "# TYPE telegram_handlers_in_flight gauge")
+	fmt.Fprintf(w, "telegram_handlers_in_flight %d\n", p.inFlight.Load())
+
+	fmt.Fprintln(w, "# HELP telegram_updates_processed_total Updates handled since start.")
+	fmt.Fprintln(w, "# TYPE telegram_updates_processed_total counter")
+	fmt.Fprintf(w, "telegram_updates_processed_total %d\n", p.processed.Load())
+
+	fmt.Fprintln(w, "# HELP telegram_updates_rejected_total Updates that could not be queued.")
+	fmt.Fprintln(w, "# TYPE telegram_updates_rejected_total counter")
+	fmt.Fprintf(w, "telegram_updates_rejected_total %d\n", p.rejected.Load())
+}
+
+// updateChatID returns the chat an update belongs to, 0 for updates without a chat
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+func shard(chatID int64, n int) int {
+	if chatID < 0 {
+		chatID = -chatID
+	}
+	return int(chatID % int64(n))
+}
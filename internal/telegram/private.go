@@ -0,0 +1,298 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+const (
+	// memberCacheTTL is how long a group membership check is trusted
+	memberCacheTTL = 10 * time.Minute
+
+	// pendingTTL is how long a private idea waits for the author to pick a group
+	pendingTTL = 30 * time.Minute
+
+	callbackGroup = "grp"
+)
+
+// membership is the cached list of allowed groups a user belongs to
+type membership struct {
+	groups    []int64
+	checkedAt time.Time
+}
+
+// pendingIdea is a private submission waiting for the author to choose a group
+type pendingIdea struct {
+	msg       *tgbotapi.Message
+	input     model.CreateIdeaInput
+	createdAt time.Time
+}
+
+// canUsePrivateChat reports whether a user may talk to the bot privately:
+// anyone when no groups are configured, otherwise admins and members of an allowed group
+func (b *Bot) canUsePrivateChat(userID int64) bool {
+	if len(b.allowedGroups) == 0 || b.admins[userID] {
+		return true
+	}
+	return len(b.memberGroups(userID)) > 0
+}
+
+// memberGroups returns the allowed groups the user is a member of, sorted by ID
+func (b *Bot) memberGroups(userID int64) []int64 {
+	b.membersMu.Lock()
+	cached, ok := b.members[userID]
+	b.membersMu.Unlock()
+	if ok && time.Since(cached.checkedAt) < memberCacheTTL {
+		return cached.groups
+	}
+
+	var groups []int64
+	for groupID := range b.allowedGroups {
+		if b.isMember(groupID, userID) {
+			groups = append(groups, groupID)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+
+	// Only positive results are cached, so users who just joined are not locked out
+	if len(groups) > 0 {
+		b.membersMu.Lock()
+		b.members[userID] = membership{groups: groups, checkedAt: time.Now()}
+		b.membersMu.Unlock()
+	}
+
+	return groups
+}
+
+// isMember checks with Telegram whether the user belongs to the group
+func (b *Bot) isMember(groupID, userID int64) bool {
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: groupID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("Failed to check membership of user %d in chat %d: %v", userID, groupID, err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator() || member.Status == "member" ||
+		(member.Status == "restricted" && member.IsMember)
+}
+
+// chooseGroup associates a private submission with a group. It returns false when
+// the author has to pick one first; the idea then continues from handleGroupChoice.
+func (b *Bot) chooseGroup(msg *tgbotapi.Message, input *model.CreateIdeaInput) bool {
+	if !msg.Chat.IsPrivate() || len(b.allowedGroups) == 0 {
+		return true
+	}
+	// The message stays in the private chat, while the idea goes to the group
+	input.MessageChatID = msg.Chat.ID
+
+	groups := b.memberGroups(msg.From.ID)
+	if len(groups) == 0 && b.admins[msg.From.ID] {
+		// Admins may file ideas for any allowed group
+		for groupID := range b.allowedGroups {
+			groups = append(groups, groupID)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+	}
+
+	switch len(groups) {
+	case 0:
+		b.reply(msg, "⛔ Вы не состоите ни в одной из групп бота.")
+		return false
+	case 1:
+		input.TelegramChatID = groups[0]
+		return true
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		log.Printf("Failed to generate token: %v", err)
+		b.reply(msg, "❌ Произошла ошибка. Попробуйте позже.")
+		return false
+	}
+
+	b.pendingMu.Lock()
+	for t, p := range b.pending {
+		if time.Since(p.createdAt) > pendingTTL {
+			delete(b.pending, t)
+		}
+	}
+	b.pending[token] = &pendingIdea{msg: msg, input: *input, createdAt: time.Now()}
+	b.pendingMu.Unlock()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, groupID := range groups {
+		data := fmt.Sprintf("%s:%s:%d", callbackGroup, token, groupID)
+		label := b.chatTitle(groupID)
+		if project, err := b.ideaService.ProjectForChat(groupID, 0); err == nil && project != nil {
+			label += " · " + project.Name
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, data)))
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "👥 В какую группу добавить идею?")
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := b.api.Send(reply); err != nil {
+		log.Printf("Failed to send group choice: %v", err)
+	}
+	return false
+}
+
+// handleCallback dispatches inline keyboard button presses
+func (b *Bot) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	kind, payload, _ := strings.Cut(query.Data, ":")
+	switch kind {
+	case callbackGroup:
+		b.handleGroupChoice(ctx, query, payload)
+	default:
+		b.answerCallback(query, "")
+	}
+}
+
+// handleGroupChoice continues a private submission once the author picks a group
+func (b *Bot) handleGroupChoice(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) {
+	token, groupStr, _ := strings.Cut(payload, ":")
+	groupID, err := strconv.ParseInt(groupStr, 10, 64)
+	if err != nil {
+		b.answerCallback(query, "")
+		return
+	}
+
+	b.pendingMu.Lock()
+	pending, ok := b.pending[token]
+	if ok && pending.msg.From.ID == query.From.ID {
+		delete(b.pending, token)
+	}
+	b.pendingMu.Unlock()
+
+	if !ok || time.Since(pending.createdAt) > pendingTTL {
+		b.answerCallback(query, "Идея уже отправлена или устарела")
+		return
+	}
+	if pending.msg.From.ID != query.From.ID {
+		b.answerCallback(query, "")
+		return
+	}
+	// The group comes from callback data the client controls, so membership is checked again
+	if !b.allowedGroups[groupID] || !b.isMember(groupID, query.From.ID) {
+		b.answerCallback(query, "Группа недоступна")
+		return
+	}
+
+	b.answerCallback(query, "")
+	if query.Message != nil {
+		b.editMessage(query.Message, "👥 Группа: "+b.chatTitle(groupID))
+	}
+
+	input := pending.input
+	input.TelegramChatID = groupID
+	b.processIdea(ctx, pending.msg, input)
+}
+
+// askScope returns the project whose ideas /ask may search: the project of the chat,
+// or in a private chat the single project of all the user's groups. It reports false
+// when the user's groups belong to different projects.
+func (b *Bot) askScope(msg *tgbotapi.Message) (int64, bool) {
+	chats := []int64{msg.Chat.ID}
+	if msg.Chat.IsPrivate() {
+		if len(b.allowedGroups) == 0 {
+			return 0, true
+		}
+		chats = b.memberGroups(msg.From.ID)
+	}
+
+	var projectID int64
+	for i, chatID := range chats {
+		project, err := b.ideaService.ProjectForChat(chatID, int64(b.threadOf(msg)))
+		if err != nil {
+			log.Printf("Failed to resolve project of chat %d: %v", chatID, err)
+			return 0, false
+		}
+		var id int64
+		if project != nil {
+			id = project.ID
+		}
+		if i > 0 && id != projectID {
+			return 0, false
+		}
+		projectID = id
+	}
+	return projectID, true
+}
+
+// announce posts a short note about a privately submitted idea to its group
+func (b *Bot) announce(idea *model.Idea, input model.CreateIdeaInput) {
+	cfg := config.Get()
+	if !cfg.Telegram.PrivateAnnounce || input.TelegramChatID == 0 || !b.allowedGroups[input.TelegramChatID] {
+		return
+	}
+
+	author := input.TelegramFirstName
+	if input.TelegramUsername != "" {
+		author = "@" + input.TelegramUsername
+	}
+	title := idea.Title
+	if title == "" {
+		title = idea.RawText
+	}
+	if len([]rune(title)) > 100 {
+		title = string([]rune(title)[:100]) + "..."
+	}
+
+	ideaURL := fmt.Sprintf("%s/ideas/%d", cfg.Web.BaseURL, idea.ID)
+	text := fmt.Sprintf("💡 Новая идея от %s: [%s](%s)", escapeMarkdownV2(author), escapeMarkdownV2(title), escapeMarkdownV2(ideaURL))
+
+	msg := tgbotapi.NewMessage(input.TelegramChatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.DisableWebPagePreview = true
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Failed to announce idea %d in chat %d: %v", idea.ID, input.TelegramChatID, err)
+	}
+}
+
+// chatTitle returns the title of a group, falling back to its ID
+func (b *Bot) chatTitle(chatID int64) string {
+	b.membersMu.Lock()
+	title, ok := b.chatTitles[chatID]
+	b.membersMu.Unlock()
+	if ok {
+		return title
+	}
+
+	chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil || chat.Title == "" {
+		log.Printf("Failed to get title of chat %d: %v", chatID, err)
+		return strconv.FormatInt(chatID, 10)
+	}
+
+	b.membersMu.Lock()
+	b.chatTitles[chatID] = chat.Title
+	b.membersMu.Unlock()
+	return chat.Title
+}
+
+func (b *Bot) answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Printf("Failed to answer callback: %v", err)
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+)
+
+// handleTagCommand handles "/tag <id> <tag> [-tag ...]": tags prefixed with "-" are
+// removed, the others added. "/tag <id>" shows the idea's tags. Tags can be changed
+// by admins, the idea's author and its assignee.
+func (b *Bot) handleTagCommand(msg *tgbotapi.Message) {
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) == 0 {
+		b.reply(msg, "❌ Укажите номер идеи и теги.\n\nПример: `/tag 42 mobile export`, `/tag 42 -mobile` — убрать тег")
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "#"), 10, 64)
+	if err != nil {
+		b.reply(msg, "❌ Неверный номер идеи.")
+		return
+	}
+
+	idea, ok := b.chatIdea(msg, id)
+	if !ok {
+		b.reply(msg, fmt.Sprintf("❌ Идея #%d не найдена.", id))
+		return
+	}
+
+	if len(fields) > 1 {
+		if idea.MergedIntoID != 0 {
+			b.reply(msg, fmt.Sprintf("❌ Идея #%d объединена с #%d, меняйте теги у неё.", id, idea.MergedIntoID))
+			return
+		}
+
+		self := msg.From.UserName
+		isAuthor := idea.TelegramUserID == msg.From.ID
+		isAssignee := self != "" && strings.EqualFold(idea.Assignee, self)
+		if !b.admins[msg.From.ID] && !isAuthor && !isAssignee {
+			b.reply(msg, "⛔ Менять теги может администратор, автор идеи или её ответственный.")
+			return
+		}
+
+		for _, name := range fields[1:] {
+			if removed, ok := strings.CutPrefix(name, "-"); ok {
+				err = b.ideaService.RemoveTag(id, removed)
+			} else {
+				err = b.ideaService.AddTag(id, name)
+			}
+			if err != nil {
+				var validationErr *service.ValidationError
+				if errors.As(err, &validationErr) {
+					b.reply(msg, "❌ "+validationErr.Message)
+					return
+				}
+				log.Printf("Error changing tags of idea %d: %v", id, err)
+				b.reply(msg, "❌ Не удалось изменить теги. Попробуйте позже.")
+				return
+			}
+		}
+
+		if idea, err = b.ideaService.GetByID(id); err != nil {
+			log.Printf("Error getting idea %d: %v", id, err)
+			b.reply(msg, "❌ Не удалось изменить теги. Попробуйте позже.")
+			return
+		}
+	}
+
+	ideaURL := fmt.Sprintf("%s/ideas/%d", config.Get().Web.BaseURL, id)
+	if len(idea.Tags) == 0 {
+		b.replyMarkdown(msg, fmt.Sprintf("🏷 У [идеи \\#%d](%s) нет тегов", id, escapeMarkdownV2(ideaURL)))
+		return
+	}
+	b.replyMarkdown(msg, fmt.Sprintf("🏷 Теги [идеи \\#%d](%s): %s", id, escapeMarkdownV2(ideaURL), escapeMarkdownV2(formatTags(idea.Tags))))
+}
+
+// formatTags lists tag names as hashtags
+func formatTags(tags []model.Tag) string {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = "#" + t.Name
+	}
+	return strings.Join(names, " ")
+}
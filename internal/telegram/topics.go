@@ -0,0 +1,236 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// tgbotapi v5.5.1 predates forum topics: Message has no message_thread_id and
+// MessageConfig can't set it. Updates are decoded here to pick up the topic of
+// each message, and messages to topics are sent with hand-built requests.
+
+// threadTTL is how long the topic of a message is remembered for replies to it
+const threadTTL = 24 * time.Hour
+
+type messageRef struct {
+	chatID    int64
+	messageID int
+}
+
+type threadEntry struct {
+	threadID int
+	seen     time.Time
+}
+
+// topicFields are the forum fields of a message that tgbotapi does not parse
+type topicFields struct {
+	MessageID       int  `json:"message_id"`
+	MessageThreadID int  `json:"message_thread_id"`
+	IsTopicMessage  bool `json:"is_topic_message"`
+	Chat            struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	ReplyToMessage *topicFields `json:"reply_to_message"`
+}
+
+// decodeUpdate parses an update and remembers the forum topics of its messages
+func (b *Bot) decodeUpdate(data []byte) (tgbotapi.Update, error) {
+	var update tgbotapi.Update
+	if err := json.Unmarshal(data, &update); err != nil {
+		return update, err
+	}
+
+	var raw struct {
+		Message       *topicFields `json:"message"`
+		CallbackQuery *struct {
+			Message *topicFields `json:"message"`
+		} `json:"callback_query"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return update, err
+	}
+	b.rememberThreads(raw.Message)
+	if raw.CallbackQuery != nil {
+		b.rememberThreads(raw.CallbackQuery.Message)
+	}
+
+	return update, nil
+}
+
+// rememberThreads records the topic of a message and of the message it replies to
+func (b *Bot) rememberThreads(msg *topicFields) {
+	for ; msg != nil; msg = msg.ReplyToMessage {
+		// message_thread_id is also set for reply threads in ordinary supergroups, only topics count
+		if !msg.IsTopicMessage || msg.MessageThreadID == 0 {
+			continue
+		}
+
+		b.threadsMu.Lock()
+		now := time.Now()
+		if now.Sub(b.threadsSwept) > time.Hour {
+			for ref, entry := range b.threads {
+				if now.Sub(entry.seen) > threadTTL {
+					delete(b.threads, ref)
+				}
+			}
+			b.threadsSwept = now
+		}
+		b.threads[messageRef{msg.Chat.ID, msg.MessageID}] = threadEntry{threadID: msg.MessageThreadID, seen: now}
+		b.threadsMu.Unlock()
+	}
+}
+
+// threadOf returns the forum topic of a message, or 0 outside of topics
+func (b *Bot) threadOf(msg *tgbotapi.Message) int {
+	if msg == nil || msg.Chat == nil {
+		return 0
+	}
+	b.threadsMu.Lock()
+	defer b.threadsMu.Unlock()
+	return b.threads[messageRef{msg.Chat.ID, msg.MessageID}].threadID
+}
+
+// topicAllowed applies TELEGRAM_ALLOWED_TOPICS: groups with listed topics only accept
+// messages from those topics, other groups accept all of them
+func (b *Bot) topicAllowed(msg *tgbotapi.Message) bool {
+	topics, ok := b.allowedTopics[msg.Chat.ID]
+	if !ok {
+		return true
+	}
+	return topics[b.threadOf(msg)]
+}
+
+// topicCategory returns the category preset for the topic of a message
+func (b *Bot) topicCategory(msg *tgbotapi.Message) model.IdeaCategory {
+	topic := config.Topic{ChatID: msg.Chat.ID, ThreadID: b.threadOf(msg)}
+	return model.IdeaCategory(config.Get().Telegram.TopicCategories[topic])
+}
+
+// send sends a message into a forum topic, or as usual when threadID is 0
+func (b *Bot) send(c tgbotapi.MessageConfig, threadID int) (tgbotapi.Message, error) {
+	if threadID == 0 {
+		return b.api.Send(c)
+	}
+
+	params := tgbotapi.Params{}
+	if err := params.AddFirstValid("chat_id", c.ChatID, c.ChannelUsername); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	params.AddNonZero("message_thread_id", threadID)
+	params["text"] = c.Text
+	params.AddNonEmpty("parse_mode", c.ParseMode)
+	params.AddBool("disable_web_page_preview", c.DisableWebPagePreview)
+	params.AddNonZero("reply_to_message_id", c.ReplyToMessageID)
+	params.AddBool("disable_notification", c.DisableNotification)
+	params.AddBool("allow_sending_without_reply", c.AllowSendingWithoutReply)
+	if err := params.AddInterface("reply_markup", c.ReplyMarkup); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	resp, err := b.api.MakeRequest("sendMessage", params)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err != nil {
+		return sent, fmt.Errorf("failed to parse sent message: %w", err)
+	}
+	var fields topicFields
+	if err := json.Unmarshal(resp.Result, &fields); err == nil {
+		b.rememberThreads(&fields)
+	}
+	return sent, nil
+}
+
+// getUpdates calls getUpdates like BotAPI.MakeRequest, but the long poll is
+// cancelled with ctx so that shutdown doesn't wait for it
+func (b *Bot) getUpdates(ctx context.Context, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+
+	endpoint := fmt.Sprintf(tgbotapi.APIEndpoint, b.api.Token, "getUpdates")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := b.api.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp tgbotapi.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+	if !apiResp.Ok {
+		return &apiResp, &tgbotapi.Error{Code: apiResp.ErrorCode, Message: apiResp.Description}
+	}
+	return &apiResp, nil
+}
+
+// pollUpdates long-polls getUpdates until ctx is cancelled, then closes the channel.
+// It replaces GetUpdatesChan so updates go through decodeUpdate.
+func (b *Bot) pollUpdates(ctx context.Context) <-chan tgbotapi.Update {
+	ch := make(chan tgbotapi.Update, b.api.Buffer)
+
+	go func() {
+		defer close(ch)
+		offset := 0
+		for ctx.Err() == nil {
+			params := tgbotapi.Params{}
+			params.AddNonZero("offset", offset)
+			params.AddNonZero("timeout", 60)
+
+			resp, err := b.getUpdates(ctx, params)
+			if err == nil {
+				var updates []json.RawMessage
+				if err = json.Unmarshal(resp.Result, &updates); err == nil {
+					for _, data := range updates {
+						update, err := b.decodeUpdate(data)
+						if update.UpdateID >= offset {
+							offset = update.UpdateID + 1
+						}
+						if err != nil {
+							log.Printf("Skipped invalid update %d: %v", update.UpdateID, err)
+							continue
+						}
+						select {
+						case ch <- update:
+						case <-ctx.Done():
+							// Not confirmed with a newer offset, so Telegram delivers it again after restart
+							return
+						}
+					}
+					continue
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to get updates, retrying in 3 seconds: %v", err)
+			select {
+			case <-time.After(3 * time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,45 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+)
+
+// handleVoteCommand handles "/vote <id>": the user votes for an idea, or takes
+// the vote back when they have voted already. Votes rank ideas in the digests.
+func (b *Bot) handleVoteCommand(msg *tgbotapi.Message) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(msg.CommandArguments()), "#"), 10, 64)
+	if err != nil {
+		b.reply(msg, "❌ Укажите номер идеи.\n\nПример: `/vote 42`")
+		return
+	}
+
+	idea, ok := b.chatIdea(msg, id)
+	if !ok {
+		b.reply(msg, fmt.Sprintf("❌ Идея #%d не найдена.", id))
+		return
+	}
+	if idea.MergedIntoID != 0 {
+		b.reply(msg, fmt.Sprintf("❌ Идея #%d объединена с #%d, голосуйте за неё.", id, idea.MergedIntoID))
+		return
+	}
+
+	voted, err := b.ideaService.ToggleVote(id, msg.From.ID)
+	if err != nil {
+		log.Printf("Error voting for idea %d: %v", id, err)
+		b.reply(msg, "❌ Не удалось учесть голос. Попробуйте позже.")
+		return
+	}
+
+	ideaURL := fmt.Sprintf("%s/ideas/%d", config.Get().Web.BaseURL, id)
+	if !voted {
+		b.replyMarkdown(msg, fmt.Sprintf("👎 Голос за [идею \\#%d](%s) отозван", id, escapeMarkdownV2(ideaURL)))
+		return
+	}
+	b.replyMarkdown(msg, fmt.Sprintf("👍 Голос за [идею \\#%d](%s) учтён", id, escapeMarkdownV2(ideaURL)))
+}
@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/josinSbazin/idea-bot/internal/config"
+)
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	// maxWebhookBody caps the size of an update accepted from Telegram
+	maxWebhookBody = 1 << 20
+
+	// webhookQueueWait is how long a webhook request waits for room in a full queue
+	webhookQueueWait = 5 * time.Second
+)
+
+// WebhookPath returns the secret path Telegram posts updates to.
+// It is derived from the webhook secret so it cannot be guessed from the public URL.
+func WebhookPath() string {
+	sum := sha256.Sum256([]byte(config.Get().Telegram.WebhookSecret))
+	return "/telegram/" + hex.EncodeToString(sum[:16])
+}
+
+// WebhookHandler receives updates pushed by Telegram. Requests without the
+// matching X-Telegram-Bot-Api-Secret-Token header are rejected.
+func (b *Bot) WebhookHandler() http.Handler {
+	secret := []byte(config.Get().Telegram.WebhookSecret)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := []byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token"))
+		if subtle.ConstantTimeCompare(token, secret) != 1 {
+			log.Printf("Rejected webhook request from %s: bad secret token", r.RemoteAddr)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			log.Printf("Failed to read webhook update: %v", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		update, err := b.decodeUpdate(data)
+		if err != nil {
+			log.Printf("Invalid webhook update: %v", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		// Acknowledge once queued. When the queue stays full, a non-2xx answer
+		// makes Telegram redeliver the update later.
+		submitCtx, cancel := context.WithTimeout(r.Context(), webhookQueueWait)
+		defer cancel()
+		if err := b.pool.submit(submitCtx, update); err != nil {
+			log.Printf("Rejected update %d: %v", update.UpdateID, err)
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// startWebhook registers the webhook with Telegram and removes it on shutdown.
+// Updates arrive through WebhookHandler mounted on the web server.
+func (b *Bot) startWebhook(ctx context.Context) error {
+	cfg := config.Get()
+
+	baseURL := cfg.Telegram.WebhookURL
+	if baseURL == "" {
+		baseURL = cfg.Web.BaseURL
+	}
+	webhookURL := strings.TrimSuffix(baseURL, "/") + WebhookPath()
+
+	params := tgbotapi.Params{}
+	params["url"] = webhookURL
+	params["secret_token"] = cfg.Telegram.WebhookSecret
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	log.Printf("Telegram webhook registered at %s/telegram/...", strings.TrimSuffix(baseURL, "/"))
+
+	<-ctx.Done()
+	log.Println("Telegram bot stopping, removing webhook...")
+
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Failed to delete webhook: %v", err)
+	}
+	return ctx.Err()
+}
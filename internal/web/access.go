@@ -0,0 +1,47 @@
+package web
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+type scopeKey struct{}
+
+// withProjectScope limits a request to the ideas of a project
+func withProjectScope(r *http.Request, project *model.Project) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), scopeKey{}, project))
+}
+
+// projectScope returns the project a request is limited to, or nil for administrators
+func projectScope(r *http.Request) *model.Project {
+	project, _ := r.Context().Value(scopeKey{}).(*model.Project)
+	return project
+}
+
+// scopeID returns the ID of the project a request is limited to, 0 for administrators
+func scopeID(r *http.Request) int64 {
+	if project := projectScope(r); project != nil {
+		return project.ID
+	}
+	return 0
+}
+
+// canAccess reports whether the request may see the idea
+func canAccess(r *http.Request, idea *model.Idea) bool {
+	project := projectScope(r)
+	return project == nil || idea.ProjectID == project.ID
+}
+
+// adminOnly rejects requests limited to a project; pages that are not scoped
+// to a project, like themes and the digest, are for administrators only
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if projectScope(r) != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+)
+
+// apiMaxLimit caps the page size of /api/ideas
+const apiMaxLimit = 200
+
+// handleAPIIdeas lists ideas at /api/ideas with the filters of the web list
+// and a cursor for the next page
+func (h *Handler) handleAPIIdeas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter := ideaFilter(r)
+	filter.Limit = ideasPageSize
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		filter.Limit = min(limit, apiMaxLimit)
+	}
+
+	page, err := h.ideaService.ListPage(filter)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			writeJSONError(w, http.StatusBadRequest, validationErr.Message)
+			return
+		}
+		log.Printf("Error listing ideas: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	ideas := page.Ideas
+	if ideas == nil {
+		ideas = []*model.Idea{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ideas":       ideas,
+		"next_cursor": page.NextCursor,
+	})
+}
+
+// handleAPIIdea serves JSON endpoints under /api/ideas/{id}/...
+func (h *Handler) handleAPIIdea(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/ideas/")
+	idStr, sub, _ := strings.Cut(path, "/")
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "idea not found")
+		return
+	}
+
+	if idea, err := h.ideaService.GetByID(id); err != nil || !canAccess(r, idea) {
+		writeJSONError(w, http.StatusNotFound, "idea not found")
+		return
+	}
+
+	switch {
+	case sub == "reanalyze" && r.Method == http.MethodPost:
+		h.reenrichAsync(id)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"id": id, "status": "reanalyzing"})
+	case sub == "versions" && r.Method == http.MethodGet:
+		versions, err := h.ideaService.ListEnrichmentVersions(id)
+		if err != nil {
+			log.Printf("Error listing versions of idea %d: %v", id, err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		writeJSON(w, http.StatusOK, versions)
+	default:
+		writeJSONError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// splitList reads a comma-separated query parameter
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
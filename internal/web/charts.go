@@ -0,0 +1,89 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// Charts are rendered on the server as inline SVG, so the dashboard needs no scripts.
+// Bars and labels are styled by the .chart rules of the layout.
+
+const (
+	chartWidth     = 640
+	columnHeight   = 160
+	barRowHeight   = 24
+	barLabelWidth  = 150
+	barCountWidth  = 40
+	chartTextInset = 4
+)
+
+// weekChart draws the ideas-per-week column chart with the count above every column
+func weekChart(weeks []model.WeekCount) template.HTML {
+	if len(weeks) == 0 {
+		return ""
+	}
+
+	maxCount := 0
+	for _, w := range weeks {
+		maxCount = max(maxCount, w.Count)
+	}
+
+	const top, bottom = 16, 20
+	slot := float64(chartWidth) / float64(len(weeks))
+	plot := float64(columnHeight - top - bottom)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" role="img" aria-label="Идей в неделю">`, chartWidth, columnHeight)
+	for i, w := range weeks {
+		height := 0.0
+		if maxCount > 0 {
+			height = plot * float64(w.Count) / float64(maxCount)
+		}
+		x := slot * float64(i)
+		y := float64(columnHeight-bottom) - height
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s: %d</title></rect>`,
+			x+slot*0.15, y, slot*0.7, height, w.Week.Format("02.01.2006"), w.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`, x+slot/2, y-chartTextInset, w.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" class="chart-axis">%s</text>`,
+			x+slot/2, columnHeight-chartTextInset, w.Week.Format("02.01"))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// barChart draws a horizontal bar per bucket with its label on the left and count on the right
+func barChart(buckets []model.StatBucket) template.HTML {
+	if len(buckets) == 0 {
+		return ""
+	}
+
+	maxCount := 0
+	for _, bucket := range buckets {
+		maxCount = max(maxCount, bucket.Count)
+	}
+
+	plot := float64(chartWidth - barLabelWidth - barCountWidth)
+	height := barRowHeight * len(buckets)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" role="img">`, chartWidth, height)
+	for i, bucket := range buckets {
+		width := 0.0
+		if maxCount > 0 {
+			width = plot * float64(bucket.Count) / float64(maxCount)
+		}
+		y := barRowHeight * i
+		label := template.HTMLEscapeString(bucket.Label)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`,
+			barLabelWidth-2*chartTextInset, y+barRowHeight/2+chartTextInset, label)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d"><title>%s: %d</title></rect>`,
+			barLabelWidth, y+chartTextInset, width, barRowHeight-2*chartTextInset, label, bucket.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%d</text>`,
+			float64(barLabelWidth)+width+float64(chartTextInset), y+barRowHeight/2+chartTextInset, bucket.Count)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
@@ -0,0 +1,315 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/config"
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
+)
+
+// exportPageSize is how many ideas an export loads from the database at a time
+const exportPageSize = 200
+
+// maxExportTitle is the length in characters of the title made from the text of an unanalyzed idea
+const maxExportTitle = 80
+
+// exportFormat describes a file format ideas can be exported to
+type exportFormat struct {
+	Label       string
+	Extension   string
+	ContentType string
+	// write returns the function that writes one idea; finish flushes what is left
+	write func(w *bufio.Writer) (each func(*model.Idea) error, finish func() error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {Label: "CSV", Extension: "csv", ContentType: "text/csv; charset=utf-8", write: writeCSV},
+	"jsonl": {Label: "JSON Lines", Extension: "jsonl", ContentType: "application/x-ndjson", write: writeJSONL},
+	"md":    {Label: "Markdown", Extension: "md", ContentType: "text/markdown; charset=utf-8", write: writeMarkdown},
+}
+
+// exportFormatOrder is the order of the export buttons on the idea list
+var exportFormatOrder = []string{"csv", "jsonl", "md"}
+
+// exportLink is an export button of the idea list
+type exportLink struct {
+	Label string
+	URL   string
+}
+
+// exportLinks returns links that export the ideas matching the filters of the current list
+func exportLinks(r *http.Request) []exportLink {
+	query := r.URL.Query()
+	query.Del("cursor")
+
+	links := make([]exportLink, 0, len(exportFormatOrder))
+	for _, name := range exportFormatOrder {
+		query.Set("format", name)
+		links = append(links, exportLink{Label: exportFormats[name].Label, URL: "/ideas/export?" + query.Encode()})
+	}
+	return links
+}
+
+// handleExport streams every idea matching the filters of the idea list as a file download
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format, ok := exportFormats[r.URL.Query().Get("format")]
+	if !ok {
+		http.Error(w, "Неизвестный формат, доступны csv, jsonl и md", http.StatusBadRequest)
+		return
+	}
+
+	filter := ideaFilter(r)
+	filter.Cursor = ""
+	filter.Limit = exportPageSize
+
+	filename := fmt.Sprintf("ideas-%s.%s", time.Now().Format("2006-01-02"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// The response is written while ideas are loaded, so a failure halfway
+	// can only be logged and leaves a truncated file
+	bw := bufio.NewWriter(w)
+	each, finish := format.write(bw)
+	if err := h.ideaService.Each(filter, each); err != nil {
+		log.Printf("Error exporting ideas: %v", err)
+	}
+	if err := finish(); err != nil {
+		log.Printf("Error exporting ideas: %v", err)
+		return
+	}
+	if err := bw.Flush(); err != nil {
+		log.Printf("Error exporting ideas: %v", err)
+	}
+}
+
+// csvColumns are the columns of a CSV export; lists are joined with "; "
+var csvColumns = []string{
+	"id", "created_at", "updated_at", "status", "title", "summary", "category", "priority",
+	"complexity", "affected_components", "user_story", "acceptance_criteria", "technical_notes",
+	"related_features", "potential_risks", "reach", "impact", "confidence", "effort", "score",
+	"tags", "assignee", "author", "project_id", "telegram_chat_id", "raw_text", "url",
+}
+
+// writeCSV writes ideas as CSV with one row per idea and the analysis flattened into columns.
+// The file starts with a byte order mark so that Excel reads it as UTF-8.
+func writeCSV(w *bufio.Writer) (func(*model.Idea) error, func() error) {
+	w.WriteString("\ufeff")
+	cw := csv.NewWriter(w)
+	// A failed header is reported by the first row or by finish when there are no rows
+	headerErr := cw.Write(csvColumns)
+
+	each := func(idea *model.Idea) error {
+		if headerErr != nil {
+			return headerErr
+		}
+		enriched := idea.Enriched
+		if enriched == nil {
+			enriched = &model.EnrichedIdea{}
+		}
+		var reach, impact, confidence, effort, score string
+		if idea.Scoring.IsSet() {
+			reach = strconv.Itoa(idea.Scoring.Reach)
+			impact = formatFloat(idea.Scoring.Impact)
+			confidence = strconv.Itoa(idea.Scoring.Confidence)
+			effort = formatFloat(idea.Scoring.Effort)
+			score = formatFloat(idea.Score)
+		}
+		projectID := ""
+		if idea.ProjectID != 0 {
+			projectID = strconv.FormatInt(idea.ProjectID, 10)
+		}
+
+		err := cw.Write([]string{
+			strconv.FormatInt(idea.ID, 10),
+			idea.CreatedAt.Format(time.RFC3339),
+			idea.UpdatedAt.Format(time.RFC3339),
+			string(idea.Status),
+			csvCell(idea.Title),
+			csvCell(enriched.Summary),
+			string(idea.Category),
+			string(idea.Priority),
+			string(idea.Complexity),
+			csvCell(strings.Join(idea.AffectedComponents, "; ")),
+			csvCell(enriched.UserStory),
+			csvCell(strings.Join(enriched.AcceptanceCriteria, "; ")),
+			csvCell(enriched.TechnicalNotes),
+			csvCell(strings.Join(enriched.RelatedFeatures, "; ")),
+			csvCell(strings.Join(enriched.PotentialRisks, "; ")),
+			reach, impact, confidence, effort, score,
+			csvCell(strings.Join(tagNames(idea), "; ")),
+			csvCell(idea.Assignee),
+			csvCell(ideaAuthor(idea)),
+			projectID,
+			strconv.FormatInt(idea.TelegramChatID, 10),
+			csvCell(idea.RawText),
+			ideaURL(idea),
+		})
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+
+	finish := func() error {
+		cw.Flush()
+		if headerErr != nil {
+			return headerErr
+		}
+		return cw.Error()
+	}
+
+	return each, finish
+}
+
+// csvCell escapes free text that a spreadsheet would run as a formula, e.g. a title
+// starting with "=HYPERLINK(", by prefixing it with a quote
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// exportedIdea is a JSON Lines record: the idea with its analysis parsed
+type exportedIdea struct {
+	*model.Idea
+	Enriched *model.EnrichedIdea `json:"enriched,omitempty"`
+}
+
+// writeJSONL writes one JSON object per line with every field of the idea and its analysis
+func writeJSONL(w *bufio.Writer) (func(*model.Idea) error, func() error) {
+	enc := json.NewEncoder(w)
+	each := func(idea *model.Idea) error {
+		// The parsed analysis replaces its raw JSON
+		record := *idea
+		record.EnrichedJSON = ""
+		return enc.Encode(exportedIdea{Idea: &record, Enriched: idea.Enriched})
+	}
+	return each, func() error { return nil }
+}
+
+// writeMarkdown writes a document with a section per idea, meant to be pasted into planning docs
+func writeMarkdown(w *bufio.Writer) (func(*model.Idea) error, func() error) {
+	fmt.Fprintf(w, "# Идеи\n\nВыгружено %s\n", time.Now().Format("02.01.2006 15:04"))
+	count := 0
+
+	each := func(idea *model.Idea) error {
+		count++
+
+		fmt.Fprintf(w, "\n## [#%d %s](%s)\n\n", idea.ID, exportTitle(idea), ideaURL(idea))
+
+		facts := []string{"**Статус:** " + idea.Status.Label()}
+		if idea.Category != "" {
+			facts = append(facts, "**Категория:** "+idea.Category.Label())
+		}
+		if idea.Priority != "" {
+			facts = append(facts, "**Приоритет:** "+idea.Priority.Label())
+		}
+		if idea.Complexity != "" {
+			facts = append(facts, "**Сложность:** "+idea.Complexity.Label())
+		}
+		if idea.Scoring.IsSet() {
+			facts = append(facts, "**RICE:** "+formatFloat(idea.Score))
+		}
+		if idea.Assignee != "" {
+			facts = append(facts, "**Ответственный:** @"+idea.Assignee)
+		}
+		if tags := tagNames(idea); len(tags) > 0 {
+			facts = append(facts, "**Теги:** "+strings.Join(tags, ", "))
+		}
+		fmt.Fprintf(w, "%s\n", strings.Join(facts, " · "))
+
+		e := idea.Enriched
+		if e == nil {
+			// Not analyzed yet, the author's text is all there is
+			fmt.Fprintf(w, "\n> %s\n", strings.ReplaceAll(strings.TrimSpace(idea.RawText), "\n", "\n> "))
+			return nil
+		}
+
+		if e.Summary != "" {
+			fmt.Fprintf(w, "\n%s\n", e.Summary)
+		}
+		if e.UserStory != "" {
+			fmt.Fprintf(w, "\n**User story:** %s\n", e.UserStory)
+		}
+		if len(idea.AffectedComponents) > 0 {
+			fmt.Fprintf(w, "\n**Компоненты:** %s\n", strings.Join(idea.AffectedComponents, ", "))
+		}
+		writeMarkdownList(w, "Критерии приёмки", e.AcceptanceCriteria)
+		if e.TechnicalNotes != "" {
+			fmt.Fprintf(w, "\n**Технические заметки:** %s\n", e.TechnicalNotes)
+		}
+		writeMarkdownList(w, "Риски", e.PotentialRisks)
+		return nil
+	}
+
+	finish := func() error {
+		if count == 0 {
+			fmt.Fprintf(w, "\nИдей не найдено\n")
+		}
+		return nil
+	}
+
+	return each, finish
+}
+
+// writeMarkdownList writes a bold heading followed by a bulleted list, nothing when the list is empty
+func writeMarkdownList(w *bufio.Writer, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n**%s:**\n\n", heading)
+	for _, item := range items {
+		fmt.Fprintf(w, "- %s\n", item)
+	}
+}
+
+// ideaURL returns the link to the idea in the web UI
+func ideaURL(idea *model.Idea) string {
+	return fmt.Sprintf("%s/ideas/%d", strings.TrimSuffix(config.Get().Web.BaseURL, "/"), idea.ID)
+}
+
+// exportTitle returns the title of the idea, or the start of its text before it is analyzed
+func exportTitle(idea *model.Idea) string {
+	if idea.Title != "" {
+		return idea.Title
+	}
+	words := []rune(strings.Join(strings.Fields(idea.RawText), " "))
+	if len(words) > maxExportTitle {
+		return string(words[:maxExportTitle]) + "..."
+	}
+	return string(words)
+}
+
+// ideaAuthor returns @username of the author, or their first name when they have none
+func ideaAuthor(idea *model.Idea) string {
+	if idea.TelegramUsername != "" {
+		return "@" + idea.TelegramUsername
+	}
+	return idea.TelegramFirstName
+}
+
+func tagNames(idea *model.Idea) []string {
+	names := make([]string, len(idea.Tags))
+	for i, t := range idea.Tags {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
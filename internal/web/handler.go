@@ -1,11 +1,16 @@
 package web
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -18,12 +23,27 @@ import (
 //go:embed templates/*.html
 var templatesFS embed.FS
 
+// ideasPageSize is how many ideas the list shows per page
+const ideasPageSize = 50
+
+// MetricsSource writes metrics in the Prometheus text format
+type MetricsSource interface {
+	WriteMetrics(w io.Writer)
+}
+
 type Handler struct {
-	ideaService *service.IdeaService
-	templateMap map[string]*template.Template
+	ideaService    *service.IdeaService
+	digestService  *service.DigestService
+	themeService   *service.ThemeService
+	projectService *service.ProjectService
+	tagService     *service.TagService
+	statsService   *service.StatsService
+	metrics        []MetricsSource
+	reenrich       *reenrichQueue
+	templateMap    map[string]*template.Template
 }
 
-func NewHandler(ideaService *service.IdeaService) (*Handler, error) {
+func NewHandler(ideaService *service.IdeaService, digestService *service.DigestService, themeService *service.ThemeService, projectService *service.ProjectService, tagService *service.TagService, statsService *service.StatsService) (*Handler, error) {
 	funcMap := template.FuncMap{
 		"truncate": func(s string, n int) string {
 			if len(s) <= n {
@@ -49,14 +69,21 @@ func NewHandler(ideaService *service.IdeaService) (*Handler, error) {
 		"join": func(arr []string, sep string) string {
 			return strings.Join(arr, sep)
 		},
+		"linkIdeas": linkIdeas,
+		"fileSize":  fileSize,
+		"weekChart": weekChart,
+		"barChart":  barChart,
+		"lines": func(arr []string) string {
+			return strings.Join(arr, "\n")
+		},
 	}
 
-	// Parse each page template separately with layout
+	// Parse each page template separately with layout and the shared forms
 	templates := make(map[string]*template.Template)
-	pages := []string{"ideas.html", "idea.html"}
+	pages := []string{"ideas.html", "idea.html", "digest.html", "themes.html", "ask.html", "versions.html", "edit.html", "projects.html", "project.html", "bulk.html", "tags.html", "breakdown.html", "dashboard.html"}
 
 	for _, page := range pages {
-		tmpl, err := template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/layout.html", "templates/"+page)
+		tmpl, err := template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/layout.html", "templates/forms.html", "templates/"+page)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse template %s: %w", page, err)
 		}
@@ -64,11 +91,22 @@ func NewHandler(ideaService *service.IdeaService) (*Handler, error) {
 	}
 
 	return &Handler{
-		ideaService:  ideaService,
-		templateMap:  templates,
+		ideaService:    ideaService,
+		digestService:  digestService,
+		themeService:   themeService,
+		projectService: projectService,
+		tagService:     tagService,
+		statsService:   statsService,
+		reenrich:       newReenrichQueue(ideaService),
+		templateMap:    templates,
 	}, nil
 }
 
+// AddMetrics registers a source exposed on /metrics
+func (h *Handler) AddMetrics(source MetricsSource) {
+	h.metrics = append(h.metrics, source)
+}
+
 // SetupRoutes configures HTTP routes
 func (h *Handler) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
@@ -76,14 +114,27 @@ func (h *Handler) SetupRoutes() http.Handler {
 	mux.HandleFunc("/", h.handleIndex)
 	mux.HandleFunc("/ideas", h.handleIdeas)
 	mux.HandleFunc("/ideas/", h.handleIdeaDetail)
+	mux.HandleFunc("/ideas/export", h.handleExport)
+	mux.HandleFunc("/attachments/", h.handleAttachment)
+	mux.HandleFunc("/ask", h.handleAsk)
+	mux.HandleFunc("/dashboard", h.handleDashboard)
+	mux.HandleFunc("/themes", adminOnly(h.handleThemes))
+	mux.HandleFunc("/digest", adminOnly(h.handleDigest))
+	mux.HandleFunc("/digest/preview", adminOnly(h.handleDigestPreview))
+	mux.HandleFunc("/projects", adminOnly(h.handleProjects))
+	mux.HandleFunc("/projects/", adminOnly(h.handleProjectDetail))
+	mux.HandleFunc("/tags", adminOnly(h.handleTags))
 	mux.HandleFunc("/health", h.handleHealth)
+	mux.HandleFunc("/metrics", adminOnly(h.handleMetrics))
+	mux.HandleFunc("/api/ideas", h.handleAPIIdeas)
+	mux.HandleFunc("/api/ideas/", h.handleAPIIdea)
 
 	// Apply middleware
 	cfg := config.Get()
 	var handler http.Handler = mux
 	handler = Recover(handler)
 	handler = Logging(handler)
-	handler = BasicAuth(cfg.Web.Username, cfg.Web.Password)(handler)
+	handler = BasicAuth(cfg.Web.Username, cfg.Web.Password, h.projectService)(handler)
 
 	return handler
 }
@@ -102,52 +153,73 @@ func (h *Handler) handleIdeas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse filters from query params
-	filter := model.IdeaFilter{
-		Limit: 100,
-	}
+	filter := ideaFilter(r)
+	filter.Limit = ideasPageSize
 
-	if status := r.URL.Query().Get("status"); status != "" {
-		for _, s := range strings.Split(status, ",") {
-			filter.Status = append(filter.Status, model.IdeaStatus(s))
+	// Administrators pick a project from the list
+	var projects []*model.Project
+	if projectScope(r) == nil {
+		var err error
+		if projects, err = h.projectService.List(); err != nil {
+			log.Printf("Error listing projects: %v", err)
 		}
 	}
 
-	if category := r.URL.Query().Get("category"); category != "" {
-		for _, c := range strings.Split(category, ",") {
-			filter.Category = append(filter.Category, model.IdeaCategory(c))
+	page, err := h.ideaService.ListPage(filter)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			http.Error(w, validationErr.Message, http.StatusBadRequest)
+			return
 		}
+		log.Printf("Error listing ideas: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 
-	if priority := r.URL.Query().Get("priority"); priority != "" {
-		for _, p := range strings.Split(priority, ",") {
-			filter.Priority = append(filter.Priority, model.IdeaPriority(p))
-		}
+	tags, err := h.tagService.List()
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
 	}
 
-	ideas, err := h.ideaService.List(filter)
+	// Get counts for stats
+	totalCount, _ := h.ideaService.Count(model.IdeaFilter{ProjectID: filter.ProjectID})
+	newCount, _ := h.ideaService.Count(model.IdeaFilter{Status: []model.IdeaStatus{model.CurrentWorkflow().Initial}, ProjectID: filter.ProjectID})
+	foundCount, err := h.ideaService.Count(filter)
 	if err != nil {
-		log.Printf("Error listing ideas: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		log.Printf("Error counting ideas: %v", err)
 	}
 
-	// Get counts for stats
-	totalCount, _ := h.ideaService.Count(model.IdeaFilter{})
-	newCount, _ := h.ideaService.Count(model.IdeaFilter{Status: []model.IdeaStatus{model.StatusNew}})
+	// Page links keep the filters and only move the cursor
+	pageURL := func(cursor string) string {
+		if cursor == "" {
+			return ""
+		}
+		query := r.URL.Query()
+		query.Set("cursor", cursor)
+		return "/ideas?" + query.Encode()
+	}
 
 	data := map[string]interface{}{
 		"Title":         "Список идей",
-		"Ideas":         ideas,
+		"Ideas":         page.Ideas,
 		"TotalCount":    totalCount,
 		"NewCount":      newCount,
+		"FoundCount":    foundCount,
+		"NextURL":       pageURL(page.NextCursor),
+		"PrevURL":       pageURL(page.PrevCursor),
+		"ReturnURL":     r.URL.RequestURI(),
+		"Exports":       exportLinks(r),
 		"AllStatuses":   model.AllStatuses(),
 		"AllCategories": model.AllCategories(),
 		"AllPriorities": model.AllPriorities(),
+		"AllSorts":      model.AllSorts(),
+		"Projects":      projects,
+		"Tags":          tags,
 		"Filter":        filter,
 	}
 
-	h.render(w, "ideas.html", data)
+	h.render(w, r, "ideas.html", data)
 }
 
 func (h *Handler) handleIdeasPost(w http.ResponseWriter, r *http.Request) {
@@ -157,6 +229,11 @@ func (h *Handler) handleIdeasPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	action := r.FormValue("action")
+	if strings.HasPrefix(action, "bulk_") {
+		h.handleBulk(w, r, action)
+		return
+	}
+
 	idStr := r.FormValue("id")
 
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -165,14 +242,93 @@ func (h *Handler) handleIdeasPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idea, err := h.ideaService.GetByID(id)
+	if err != nil || !canAccess(r, idea) {
+		http.NotFound(w, r)
+		return
+	}
+	// A merged duplicate is read-only, changes go to the idea it was merged into
+	if idea.MergedIntoID != 0 {
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d?merged=%d", idea.MergedIntoID, idea.ID), http.StatusFound)
+		return
+	}
+
 	switch action {
 	case "update_status":
 		status := model.IdeaStatus(r.FormValue("status"))
-		if err := h.ideaService.UpdateStatus(id, status); err != nil {
+		if err := h.ideaService.UpdateStatus(id, status, r.FormValue("comment")); err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderIdea(w, r, idea, validationErr.Message)
+				return
+			}
 			log.Printf("Error updating status: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
+	case "update_assignee":
+		if err := h.ideaService.Assign(id, r.FormValue("assignee")); err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				http.Error(w, validationErr.Message, http.StatusBadRequest)
+				return
+			}
+			log.Printf("Error assigning idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "update_scoring":
+		scoring, err := scoringFromForm(r)
+		if err == nil {
+			err = h.ideaService.UpdateScoring(id, scoring)
+		}
+		if err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderIdea(w, r, idea, validationErr.Message)
+				return
+			}
+			log.Printf("Error scoring idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "reset_scoring":
+		if err := h.ideaService.ResetScoring(id); err != nil {
+			log.Printf("Error resetting scoring of idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "add_tag", "remove_tag":
+		if action == "add_tag" {
+			err = h.ideaService.AddTag(id, r.FormValue("tag"))
+		} else {
+			err = h.ideaService.RemoveTag(id, r.FormValue("tag"))
+		}
+		if err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderIdea(w, r, idea, validationErr.Message)
+				return
+			}
+			log.Printf("Error changing tags of idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
 	case "update_notes":
 		notes := r.FormValue("notes")
 		if err := h.ideaService.UpdateAdminNotes(id, notes); err != nil {
@@ -180,6 +336,113 @@ func (h *Handler) handleIdeasPost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
+	case "reanalyze":
+		h.reenrichAsync(id)
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d?reanalyzing=1", id), http.StatusFound)
+		return
+	case "rollback":
+		version, err := strconv.Atoi(r.FormValue("version"))
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		if err := h.ideaService.RollbackEnrichment(id, version); err != nil {
+			log.Printf("Error rolling back idea %d to version %d: %v", id, version, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d/versions", id), http.StatusFound)
+		return
+	case "update_enriched":
+		edited := enrichedFromForm(r)
+		if _, err := h.ideaService.UpdateEnriched(id, edited); err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderEditForm(w, r, idea, edited, validationErr.Message)
+				return
+			}
+			log.Printf("Error updating idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "clear_overrides":
+		if err := h.ideaService.ClearOverrides(id); err != nil {
+			log.Printf("Error clearing overrides of idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "link":
+		otherID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(r.FormValue("other")), "#"), 10, 64)
+		if err == nil {
+			err = h.ideaService.Link(id, model.IdeaRelation(r.FormValue("relation")), otherID, scopeID(r))
+		} else {
+			err = &service.ValidationError{Message: "Укажите номер идеи, с которой нужно связать эту"}
+		}
+		if err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderIdea(w, r, idea, validationErr.Message)
+				return
+			}
+			log.Printf("Error linking idea %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d#links", id), http.StatusFound)
+		return
+	case "unlink":
+		linkID, _ := strconv.ParseInt(r.FormValue("link_id"), 10, 64)
+		if err := h.ideaService.Unlink(id, linkID); err != nil {
+			log.Printf("Error removing link %d of idea %d: %v", linkID, id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d#links", id), http.StatusFound)
+		return
+	case "breakdown":
+		h.handleBreakdown(w, r, id)
+		return
+	case "create_children":
+		h.createChildren(w, r, id)
+		return
+	case "merge":
+		intoID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(r.FormValue("into")), "#"), 10, 64)
+		if err != nil {
+			idea, err := h.ideaService.GetByID(id)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			h.renderIdea(w, r, idea, "Укажите номер идеи, с которой нужно объединить эту")
+			return
+		}
+		if _, err := h.ideaService.Merge(id, intoID, scopeID(r)); err != nil {
+			var validationErr *service.ValidationError
+			if errors.As(err, &validationErr) {
+				idea, err := h.ideaService.GetByID(id)
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				h.renderIdea(w, r, idea, validationErr.Message)
+				return
+			}
+			log.Printf("Error merging idea %d into %d: %v", id, intoID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/ideas/%d?merged=%d", intoID, id), http.StatusFound)
+		return
 	case "delete":
 		if err := h.ideaService.Delete(id); err != nil {
 			log.Printf("Error deleting idea: %v", err)
@@ -194,6 +457,160 @@ func (h *Handler) handleIdeasPost(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("/ideas/%d", id), http.StatusFound)
 }
 
+// handleBulk applies an action to the ideas checked in the list and shows what was done
+func (h *Handler) handleBulk(w http.ResponseWriter, r *http.Request, action string) {
+	var ids []int64
+	for _, v := range r.Form["ids"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	// Only links back into the list are followed
+	returnURL := r.FormValue("return")
+	if !strings.HasPrefix(returnURL, "/ideas") {
+		returnURL = "/ideas"
+	}
+	if len(ids) == 0 {
+		http.Redirect(w, r, returnURL, http.StatusFound)
+		return
+	}
+
+	var result *model.BulkResult
+	var title string
+	var err error
+	scope := scopeID(r)
+	switch action {
+	case "bulk_status":
+		status := model.IdeaStatus(r.FormValue("status"))
+		title = fmt.Sprintf("Смена статуса на «%s»", status.Label())
+		result, err = h.ideaService.BulkUpdateStatus(ids, status, r.FormValue("comment"), scope)
+	case "bulk_assign":
+		assignee := strings.TrimPrefix(strings.TrimSpace(r.FormValue("assignee")), "@")
+		title = "Снятие ответственного"
+		if assignee != "" {
+			title = "Назначение @" + assignee
+		}
+		result, err = h.ideaService.BulkAssign(ids, assignee, scope)
+	case "bulk_tag":
+		tag := strings.TrimSpace(r.FormValue("tag"))
+		title = fmt.Sprintf("Добавление тега «%s»", tag)
+		result, err = h.ideaService.BulkTag(ids, tag, scope)
+	case "bulk_reenrich":
+		title = "Повторный анализ"
+		result, err = h.ideaService.BulkSelect(ids, scope)
+		if err == nil {
+			h.reenrichAsync(result.Succeeded...)
+		}
+	case "bulk_delete":
+		title = "Удаление"
+		result, err = h.ideaService.BulkDelete(ids, scope)
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			http.Error(w, validationErr.Message, http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error applying %s to %d ideas: %v", action, len(ids), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":     title,
+		"Action":    action,
+		"Result":    result,
+		"ReturnURL": returnURL,
+	}
+	h.render(w, r, "bulk.html", data)
+}
+
+// handleBreakdown asks the AI how to split an epic and shows the proposal for review.
+// Nothing is stored until the reviewed parts are posted back as create_children.
+func (h *Handler) handleBreakdown(w http.ResponseWriter, r *http.Request, id int64) {
+	idea, err := h.ideaService.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), aiRequestTimeout)
+	defer cancel()
+	extendWriteDeadline(w)
+
+	items, err := h.ideaService.ProposeBreakdown(ctx, id)
+	if err != nil || len(items) == 0 {
+		log.Printf("Error breaking down idea %d: %v", id, err)
+		h.renderIdea(w, r, idea, "Не удалось разбить идею на части, попробуйте позже")
+		return
+	}
+
+	h.renderBreakdown(w, r, idea, items, "")
+}
+
+func (h *Handler) renderBreakdown(w http.ResponseWriter, r *http.Request, idea *model.Idea, items []model.BreakdownItem, errMsg string) {
+	data := map[string]interface{}{
+		"Title": fmt.Sprintf("Разбивка идеи #%d", idea.ID),
+		"Idea":  idea,
+		"Items": items,
+		"Error": errMsg,
+	}
+
+	h.render(w, r, "breakdown.html", data)
+}
+
+// createChildren creates the parts of an epic checked on the breakdown page
+// and starts their analysis
+func (h *Handler) createChildren(w http.ResponseWriter, r *http.Request, id int64) {
+	titles := r.Form["title"]
+	descriptions := r.Form["description"]
+	var items, picked []model.BreakdownItem
+	for i, title := range titles {
+		item := model.BreakdownItem{Title: title}
+		if i < len(descriptions) {
+			item.Description = descriptions[i]
+		}
+		items = append(items, item)
+	}
+	for _, v := range r.Form["pick"] {
+		if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(items) {
+			picked = append(picked, items[i])
+		}
+	}
+
+	children, err := h.ideaService.CreateChildren(id, picked)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			idea, err := h.ideaService.GetByID(id)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			h.renderBreakdown(w, r, idea, items, validationErr.Message)
+			return
+		}
+		log.Printf("Error creating parts of idea %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, len(children))
+	for i, child := range children {
+		ids[i] = child.ID
+	}
+	h.reenrichAsync(ids...)
+
+	http.Redirect(w, r, fmt.Sprintf("/ideas/%d#links", id), http.StatusFound)
+}
+
 func (h *Handler) handleIdeaDetail(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from path /ideas/{id}
 	path := strings.TrimPrefix(r.URL.Path, "/ideas/")
@@ -202,7 +619,8 @@ func (h *Handler) handleIdeaDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := strconv.ParseInt(path, 10, 64)
+	idStr, sub, _ := strings.Cut(path, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		http.NotFound(w, r)
 		return
@@ -214,14 +632,699 @@ func (h *Handler) handleIdeaDetail(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if !canAccess(r, idea) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch sub {
+	case "":
+		// A merged duplicate lives on in the idea it was merged into
+		if idea.MergedIntoID != 0 {
+			http.Redirect(w, r, fmt.Sprintf("/ideas/%d?merged=%d", idea.MergedIntoID, idea.ID), http.StatusFound)
+			return
+		}
+	case "versions":
+		h.handleIdeaVersions(w, r, idea)
+		return
+	case "edit":
+		enriched := idea.Enriched
+		if enriched == nil {
+			enriched = &model.EnrichedIdea{Title: idea.Title}
+		}
+		h.renderEditForm(w, r, idea, enriched, "")
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	h.renderIdea(w, r, idea, "")
+}
+
+// renderIdea renders the idea page; errMsg is shown when a change was rejected
+func (h *Handler) renderIdea(w http.ResponseWriter, r *http.Request, idea *model.Idea, errMsg string) {
+	attachments, err := h.ideaService.ListAttachments(idea.ID)
+	if err != nil {
+		log.Printf("Error listing attachments of idea %d: %v", idea.ID, err)
+	}
+
+	history, err := h.ideaService.StatusHistory(idea.ID)
+	if err != nil {
+		log.Printf("Error listing status history of idea %d: %v", idea.ID, err)
+	}
+
+	var project *model.Project
+	if idea.ProjectID != 0 {
+		if project, err = h.projectService.GetByID(idea.ProjectID); err != nil {
+			log.Printf("Error getting project of idea %d: %v", idea.ID, err)
+		}
+	}
+
+	// Unscored ideas get a form prefilled with the effort of their complexity
+	scoring := idea.Scoring
+	if !scoring.IsSet() {
+		scoring.Effort = idea.Complexity.Effort()
+		if scoring.Impact == 0 {
+			scoring.Impact = 1
+		}
+	}
+
+	links, err := h.ideaService.ListLinks(idea.ID)
+	if err != nil {
+		log.Printf("Error listing links of idea %d: %v", idea.ID, err)
+	}
+
+	merges, err := h.ideaService.ListMerges(idea.ID)
+	if err != nil {
+		log.Printf("Error listing merges of idea %d: %v", idea.ID, err)
+	}
+
+	tags, err := h.tagService.List()
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+	}
+
+	workflow := model.CurrentWorkflow()
+	data := map[string]interface{}{
+		"Title":         fmt.Sprintf("Идея #%d", idea.ID),
+		"Idea":          idea,
+		"Project":       project,
+		"Attachments":   attachments,
+		"StatusHistory": history,
+		"Merges":        merges,
+		"Links":         links,
+		"AllRelations":  model.AllRelations(),
+		"MergedID":      r.URL.Query().Get("merged"),
+		"State":         workflow.State(idea.Status),
+		"Transitions":   workflow.Next(idea.Status),
+		"Scoring":       scoring,
+		"ImpactLevels":  model.ImpactLevels(),
+		"Tags":          tags,
+		"Error":         errMsg,
+		"Reanalyzing":   r.URL.Query().Get("reanalyzing") != "",
+	}
+
+	h.render(w, r, "idea.html", data)
+}
+
+// handleAttachment serves the contents of an attachment at /attachments/{id}
+func (h *Handler) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/attachments/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	a, body, err := h.ideaService.OpenAttachment(r.Context(), id)
+	if err != nil {
+		log.Printf("Error opening attachment %d: %v", id, err)
+		http.NotFound(w, r)
+		return
+	}
+	defer body.Close()
+
+	if idea, err := h.ideaService.GetByID(a.IdeaID); err != nil || !canAccess(r, idea) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only images are shown inline, anything else is downloaded so uploaded HTML can't run on this origin
+	disposition := "attachment"
+	contentType := "application/octet-stream"
+	if a.IsImage() {
+		disposition = "inline"
+		contentType = a.ContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	if a.FileName != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": a.FileName})
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if a.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Error sending attachment %d: %v", id, err)
+	}
+}
+
+// handleIdeaVersions lists enrichment versions of an idea and shows a diff between two of them
+func (h *Handler) handleIdeaVersions(w http.ResponseWriter, r *http.Request, idea *model.Idea) {
+	versions, err := h.ideaService.ListEnrichmentVersions(idea.ID)
+	if err != nil {
+		log.Printf("Error listing versions of idea %d: %v", idea.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":    fmt.Sprintf("Версии анализа идеи #%d", idea.ID),
+		"Idea":     idea,
+		"Versions": versions,
+	}
+
+	// By default compare the active version with the one before it
+	a, _ := strconv.Atoi(r.URL.Query().Get("a"))
+	b, _ := strconv.Atoi(r.URL.Query().Get("b"))
+	if a == 0 && b == 0 && idea.EnrichmentVersion > 1 {
+		a, b = idea.EnrichmentVersion-1, idea.EnrichmentVersion
+	}
+
+	if a > 0 && b > 0 {
+		older, errA := h.ideaService.GetEnrichmentVersion(idea.ID, a)
+		newer, errB := h.ideaService.GetEnrichmentVersion(idea.ID, b)
+		if errA != nil || errB != nil {
+			http.Error(w, "Version not found", http.StatusNotFound)
+			return
+		}
+		data["A"] = a
+		data["B"] = b
+		data["Diff"] = model.DiffEnriched(older.Enriched, newer.Enriched)
+	}
+
+	h.render(w, r, "versions.html", data)
+}
+
+// renderEditForm shows the form for editing the AI analysis of an idea
+func (h *Handler) renderEditForm(w http.ResponseWriter, r *http.Request, idea *model.Idea, enriched *model.EnrichedIdea, errMsg string) {
+	data := map[string]interface{}{
+		"Title":           fmt.Sprintf("Редактирование идеи #%d", idea.ID),
+		"Idea":            idea,
+		"Form":            enriched,
+		"Error":           errMsg,
+		"AllCategories":   model.AllCategories(),
+		"AllPriorities":   model.AllPriorities(),
+		"AllComplexities": model.AllComplexities(),
+	}
+
+	h.render(w, r, "edit.html", data)
+}
+
+// enrichedFromForm reads the edit form; list fields are entered one item per line
+func enrichedFromForm(r *http.Request) *model.EnrichedIdea {
+	return &model.EnrichedIdea{
+		Title:              r.FormValue("title"),
+		Summary:            strings.TrimSpace(r.FormValue("summary")),
+		DetailedDesc:       strings.TrimSpace(r.FormValue("detailed_description")),
+		Category:           r.FormValue("category"),
+		Priority:           r.FormValue("priority"),
+		Complexity:         r.FormValue("complexity"),
+		AffectedComponents: splitLines(r.FormValue("affected_components")),
+		UserStory:          strings.TrimSpace(r.FormValue("user_story")),
+		AcceptanceCriteria: splitLines(r.FormValue("acceptance_criteria")),
+		PotentialRisks:     splitLines(r.FormValue("potential_risks")),
+	}
+}
+
+// scoringFromForm reads RICE factors posted from the scoring panel
+func scoringFromForm(r *http.Request) (model.Scoring, error) {
+	var scoring model.Scoring
+	var err error
+	if scoring.Reach, err = strconv.Atoi(strings.TrimSpace(r.FormValue("reach"))); err != nil {
+		return scoring, &service.ValidationError{Message: "Охват должен быть целым числом"}
+	}
+	if scoring.Impact, err = strconv.ParseFloat(r.FormValue("impact"), 64); err != nil {
+		return scoring, &service.ValidationError{Message: "Выберите влияние"}
+	}
+	if scoring.Confidence, err = strconv.Atoi(strings.TrimSpace(r.FormValue("confidence"))); err != nil {
+		return scoring, &service.ValidationError{Message: "Уверенность должна быть целым числом"}
+	}
+	effort := strings.ReplaceAll(strings.TrimSpace(r.FormValue("effort")), ",", ".")
+	if scoring.Effort, err = strconv.ParseFloat(effort, 64); err != nil {
+		return scoring, &service.ValidationError{Message: "Трудозатраты должны быть числом"}
+	}
+	return scoring, nil
+}
+
+// splitLines splits textarea input into non-empty trimmed lines
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// reenrichAsync re-enriches ideas in the background, Claude calls outlive the HTTP write timeout
+func (h *Handler) reenrichAsync(ids ...int64) {
+	h.reenrich.add(ids...)
+}
+
+// Drain waits for background re-analysis after the server has shut down
+func (h *Handler) Drain(timeout time.Duration) {
+	h.reenrich.drain(timeout)
+}
+
+// aiRequestTimeout bounds pages that wait for Claude while the user waits for the response
+const aiRequestTimeout = 60 * time.Second
+
+// extendWriteDeadline lets a page that waits for Claude outlive the server's write
+// timeout, with a margin to render the answer
+func extendWriteDeadline(w http.ResponseWriter) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(aiRequestTimeout + 10*time.Second)); err != nil {
+		log.Printf("Warning: failed to extend the write deadline: %v", err)
+	}
+}
+
+func (h *Handler) handleAsk(w http.ResponseWriter, r *http.Request) {
+	question := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	data := map[string]interface{}{
+		"Title":    "Спросить",
+		"Question": question,
+	}
+
+	if question != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), aiRequestTimeout)
+		defer cancel()
+		extendWriteDeadline(w)
+
+		answer, err := h.ideaService.Ask(ctx, question, scopeID(r))
+		if err != nil {
+			log.Printf("Error answering question: %v", err)
+			data["Error"] = "Не удалось получить ответ. Попробуйте позже."
+		} else {
+			data["Answer"] = answer
+		}
+	}
+
+	h.render(w, r, "ask.html", data)
+}
+
+// handleDashboard shows the analytics of the idea pipeline. Project members see
+// their project, administrators all ideas or the project they pick.
+func (h *Handler) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	projectID := scopeID(r)
+	var projects []*model.Project
+	if scope := projectScope(r); scope != nil {
+		projects = []*model.Project{scope}
+	} else {
+		projectID, _ = strconv.ParseInt(r.URL.Query().Get("project"), 10, 64)
+		var err error
+		if projects, err = h.projectService.List(); err != nil {
+			log.Printf("Error listing projects: %v", err)
+		}
+	}
+
+	dashboard, err := h.statsService.Dashboard(projectID)
+	if err != nil {
+		log.Printf("Error building dashboard: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Groups are named after the project they are bound to
+	for i, g := range dashboard.Groups {
+		for _, p := range projects {
+			for _, c := range p.Chats {
+				if c.ChatID == g.ChatID {
+					dashboard.Groups[i].Project = p.Name
+				}
+			}
+		}
+	}
+
+	data := map[string]interface{}{
+		"Title":     "Аналитика",
+		"Dashboard": dashboard,
+		"ProjectID": projectID,
+	}
+	if projectScope(r) == nil {
+		data["Projects"] = projects
+	}
+
+	h.render(w, r, "dashboard.html", data)
+}
+
+func (h *Handler) handleThemes(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.themeService.Notify()
+		http.Redirect(w, r, "/themes?refreshing=1", http.StatusFound)
+		return
+	}
+
+	themes, err := h.themeService.List()
+	if err != nil {
+		log.Printf("Error listing themes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	unclustered, _ := h.themeService.CountUnclustered()
+
+	data := map[string]interface{}{
+		"Title":       "Темы",
+		"Themes":      themes,
+		"Unclustered": unclustered,
+		"Refreshing":  r.URL.Query().Get("refreshing") != "",
+	}
+
+	h.render(w, r, "themes.html", data)
+}
+
+func (h *Handler) handleDigest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if err := h.digestService.SendEmail(r.Context()); err != nil {
+			log.Printf("Error sending digest: %v", err)
+			http.Error(w, "Failed to send digest: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/digest?sent=1", http.StatusFound)
+		return
+	}
+
+	digest, err := h.digestService.BuildLatest(0)
+	if err != nil {
+		log.Printf("Error building digest: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":        "Дайджест",
+		"Digest":       digest,
+		"Recipients":   h.digestService.Recipients(),
+		"EmailEnabled": h.digestService.EmailEnabled(),
+		"Sent":         r.URL.Query().Get("sent") != "",
+	}
+
+	h.render(w, r, "digest.html", data)
+}
+
+func (h *Handler) handleDigestPreview(w http.ResponseWriter, r *http.Request) {
+	digest, err := h.digestService.BuildLatest(0)
+	if err != nil {
+		log.Printf("Error building digest: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		body, err := h.digestService.RenderText(digest)
+		if err != nil {
+			log.Printf("Error rendering digest: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+		return
+	}
+
+	body, err := h.digestService.RenderHTML(digest)
+	if err != nil {
+		log.Printf("Error rendering digest: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(body))
+}
+
+func (h *Handler) handleProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.saveProject(w, r, &model.Project{})
+		return
+	}
+
+	projects, err := h.projectService.List()
+	if err != nil {
+		log.Printf("Error listing projects: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderProjects(w, r, projects, &model.Project{}, "", "")
+}
+
+func (h *Handler) renderProjects(w http.ResponseWriter, r *http.Request, projects []*model.Project, form *model.Project, chats, errMsg string) {
+	data := map[string]interface{}{
+		"Title":    "Проекты",
+		"Projects": projects,
+		"Form":     form,
+		"Chats":    chats,
+		"Error":    errMsg,
+	}
+
+	h.render(w, r, "projects.html", data)
+}
+
+// handleProjectDetail shows and updates a project at /projects/{id}
+func (h *Handler) handleProjectDetail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/projects/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	project, err := h.projectService.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		switch r.FormValue("action") {
+		case "delete":
+			if err := h.projectService.Delete(id); err != nil {
+				log.Printf("Error deleting project %d: %v", id, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/projects", http.StatusFound)
+		case "save_component":
+			h.saveComponent(w, r, project)
+		case "delete_component":
+			componentID, _ := strconv.ParseInt(r.FormValue("component_id"), 10, 64)
+			if err := h.projectService.DeleteComponent(id, componentID); err != nil {
+				log.Printf("Error deleting component %d of project %d: %v", componentID, id, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("/projects/%d#components", id), http.StatusFound)
+		default:
+			h.saveProject(w, r, project)
+		}
+		return
+	}
+
+	h.renderProject(w, r, project, formatProjectChats(project.Chats), "", r.URL.Query().Get("saved") != "")
+}
+
+func (h *Handler) renderProject(w http.ResponseWriter, r *http.Request, project *model.Project, chats, errMsg string, saved bool) {
+	data := map[string]interface{}{
+		"Title": project.Name,
+		"Form":  project,
+		"Chats": chats,
+		"Error": errMsg,
+		"Saved": saved,
+	}
+
+	h.render(w, r, "project.html", data)
+}
+
+// saveProject applies the project form to p and stores it. Validation errors are
+// shown on the form the request came from.
+func (h *Handler) saveProject(w http.ResponseWriter, r *http.Request, p *model.Project) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	chats := r.FormValue("chats")
+	p.Name = r.FormValue("name")
+	p.SystemPrompt = strings.TrimSpace(r.FormValue("system_prompt"))
+	p.WebUsername = r.FormValue("web_username")
+
+	var err error
+	p.Chats, err = parseProjectChats(chats)
+	if err == nil {
+		p.RateLimitPerUser, err = parseLimit(r.FormValue("rate_limit_per_user"))
+	}
+	if err == nil {
+		p.RateLimitGlobal, err = parseLimit(r.FormValue("rate_limit_global"))
+	}
+	if err == nil {
+		err = h.projectService.Save(p, r.FormValue("web_password"))
+	}
+
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		http.Redirect(w, r, fmt.Sprintf("/projects/%d?saved=1", p.ID), http.StatusFound)
+	case errors.As(err, &validationErr):
+		if p.ID == 0 {
+			projects, _ := h.projectService.List()
+			h.renderProjects(w, r, projects, p, chats, validationErr.Message)
+		} else {
+			h.renderProject(w, r, p, chats, validationErr.Message, false)
+		}
+	default:
+		log.Printf("Error saving project %q: %v", p.Name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// saveComponent adds or updates an entry of the project's component catalog
+func (h *Handler) saveComponent(w http.ResponseWriter, r *http.Request, project *model.Project) {
+	c := &model.Component{ProjectID: project.ID}
+	c.ID, _ = strconv.ParseInt(r.FormValue("component_id"), 10, 64)
+	c.Name = r.FormValue("component_name")
+	c.Description = r.FormValue("description")
+	c.Owner = r.FormValue("owner")
+	for _, alias := range strings.Split(r.FormValue("aliases"), ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			c.Aliases = append(c.Aliases, alias)
+		}
+	}
+
+	err := h.projectService.SaveComponent(c)
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		http.Redirect(w, r, fmt.Sprintf("/projects/%d#components", project.ID), http.StatusFound)
+	case errors.As(err, &validationErr):
+		h.renderProject(w, r, project, formatProjectChats(project.Chats), validationErr.Message, false)
+	default:
+		log.Printf("Error saving component %q of project %d: %v", c.Name, project.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleTags lists tags and creates, renames, recolors and deletes them
+func (h *Handler) handleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.renderTags(w, r, &model.Tag{}, "")
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if r.FormValue("action") == "delete" {
+		if err := h.tagService.Delete(id); err != nil {
+			log.Printf("Error deleting tag %d: %v", id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/tags", http.StatusFound)
+		return
+	}
+
+	t := &model.Tag{ID: id, Name: r.FormValue("name"), Color: r.FormValue("color")}
+	err := h.tagService.Save(t)
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/tags", http.StatusFound)
+	case errors.As(err, &validationErr):
+		h.renderTags(w, r, t, validationErr.Message)
+	default:
+		log.Printf("Error saving tag %q: %v", t.Name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) renderTags(w http.ResponseWriter, r *http.Request, form *model.Tag, errMsg string) {
+	tags, err := h.tagService.List()
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
 	data := map[string]interface{}{
-		"Title":       fmt.Sprintf("Идея #%d", idea.ID),
-		"Idea":        idea,
-		"AllStatuses": model.AllStatuses(),
+		"Title": "Теги",
+		"Tags":  tags,
+		"Form":  form,
+		"Error": errMsg,
+	}
+
+	h.render(w, r, "tags.html", data)
+}
+
+// ideaFilter reads the filters of the idea list from the query string. Project members
+// only see their project, administrators may pick one.
+func ideaFilter(r *http.Request) model.IdeaFilter {
+	query := r.URL.Query()
+	filter := model.IdeaFilter{
+		Sort:     model.SortCreated,
+		Cursor:   query.Get("cursor"),
+		Tags:     splitTags(query.Get("tag")),
+		Assignee: strings.TrimPrefix(strings.TrimSpace(query.Get("assignee")), "@"),
+	}
+	for _, s := range splitList(query.Get("status")) {
+		filter.Status = append(filter.Status, model.IdeaStatus(s))
+	}
+	for _, c := range splitList(query.Get("category")) {
+		filter.Category = append(filter.Category, model.IdeaCategory(c))
+	}
+	for _, p := range splitList(query.Get("priority")) {
+		filter.Priority = append(filter.Priority, model.IdeaPriority(p))
 	}
+	filter.MinScore, _ = strconv.ParseFloat(query.Get("min_score"), 64)
+	if sort := model.IdeaSort(query.Get("sort")); sort.IsValid() {
+		filter.Sort = sort
+	}
+	if scope := projectScope(r); scope != nil {
+		filter.ProjectID = scope.ID
+	} else {
+		filter.ProjectID, _ = strconv.ParseInt(query.Get("project"), 10, 64)
+	}
+	return filter
+}
+
+// splitTags reads a comma-separated list of tag names
+func splitTags(s string) []string {
+	var tags []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#")); name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// parseProjectChats reads one chat per line, as "chat_id" or "chat_id:thread_id"
+func parseProjectChats(s string) ([]model.ProjectChat, error) {
+	var chats []model.ProjectChat
+	for _, line := range splitLines(s) {
+		chatStr, threadStr, hasThread := strings.Cut(line, ":")
+		chat := model.ProjectChat{}
+		var err error
+		chat.ChatID, err = strconv.ParseInt(strings.TrimSpace(chatStr), 10, 64)
+		if err == nil && hasThread {
+			chat.ThreadID, err = strconv.ParseInt(strings.TrimSpace(threadStr), 10, 64)
+		}
+		if err != nil || chat.ChatID == 0 || chat.ThreadID < 0 {
+			return nil, &service.ValidationError{Message: fmt.Sprintf("Неверный чат %q: ожидается chat_id или chat_id:thread_id", line)}
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+func formatProjectChats(chats []model.ProjectChat) string {
+	lines := make([]string, len(chats))
+	for i, c := range chats {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
 
-	h.render(w, "idea.html", data)
+func parseLimit(s string) (int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, &service.ValidationError{Message: fmt.Sprintf("Неверный лимит %q", s)}
+	}
+	return n, nil
 }
 
 func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -230,7 +1333,37 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
-func (h *Handler) render(w http.ResponseWriter, name string, data interface{}) {
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, source := range h.metrics {
+		source.WriteMetrics(w)
+	}
+}
+
+// fileSize formats a size in bytes for display, like "1.5 МБ"
+func fileSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d КБ", n>>10)
+	default:
+		return fmt.Sprintf("%d Б", n)
+	}
+}
+
+var ideaRefRe = regexp.MustCompile(`#(\d+)`)
+
+// linkIdeas escapes text and turns #ID references into links to idea pages
+func linkIdeas(text string) template.HTML {
+	escaped := template.HTMLEscapeString(text)
+	return template.HTML(ideaRefRe.ReplaceAllString(escaped, `<a href="/ideas/$1">#$1</a>`))
+}
+
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, name string, data map[string]interface{}) {
+	// The layout hides administrator pages from project members
+	data["Scope"] = projectScope(r)
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	tmpl, ok := h.templateMap[name]
 	if !ok {
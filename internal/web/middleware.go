@@ -3,10 +3,18 @@ package web
 import (
 	"crypto/subtle"
 	"net/http"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/model"
 )
 
-// BasicAuth middleware for simple authentication
-func BasicAuth(username, password string) func(http.Handler) http.Handler {
+// ProjectAuthenticator checks web credentials of project members
+type ProjectAuthenticator interface {
+	Authenticate(username, password string) (*model.Project, bool)
+}
+
+// BasicAuth middleware for simple authentication. The configured credentials give
+// access to everything, project credentials limit the request to the project's ideas.
+func BasicAuth(username, password string, projects ProjectAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip auth for health endpoint
@@ -25,12 +33,18 @@ func BasicAuth(username, password string) func(http.Handler) http.Handler {
 			userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
 			passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
 
-			if !userMatch || !passMatch {
+			if userMatch && passMatch {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			project, ok := projects.Authenticate(user, pass)
+			if !ok {
 				unauthorized(w)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withProjectScope(r, project))
 		})
 	}
 }
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/josinSbazin/idea-bot/internal/domain/service"
+)
+
+// reenrichQueueSize is how many ideas can wait for a background re-analysis
+const reenrichQueueSize = 1000
+
+// reenrichQueue re-analyzes ideas in the background, as Claude calls outlive the HTTP
+// write timeout. Ideas are re-analyzed one by one, so a bulk action doesn't flood the
+// Claude API, and the queue is drained on shutdown.
+type reenrichQueue struct {
+	ideaService *service.IdeaService
+	ids         chan int64
+
+	// ctx is only cancelled when draining times out
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func newReenrichQueue(ideaService *service.IdeaService) *reenrichQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &reenrichQueue{
+		ideaService: ideaService,
+		ids:         make(chan int64, reenrichQueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go q.work()
+	return q
+}
+
+func (q *reenrichQueue) work() {
+	defer close(q.done)
+	for id := range q.ids {
+		if q.ctx.Err() != nil {
+			// Draining timed out, the rest of the queue is dropped
+			continue
+		}
+		ctx, cancel := context.WithTimeout(q.ctx, 2*time.Minute)
+		if _, err := q.ideaService.Reenrich(ctx, id); err != nil {
+			log.Printf("Error re-enriching idea %d: %v", id, err)
+		}
+		cancel()
+	}
+}
+
+// add queues ideas for re-analysis; ideas that don't fit in the queue are skipped
+func (q *reenrichQueue) add(ids ...int64) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	for _, id := range ids {
+		if q.closed {
+			log.Printf("Skipped re-enriching idea %d: shutting down", id)
+			continue
+		}
+		select {
+		case q.ids <- id:
+		default:
+			log.Printf("Skipped re-enriching idea %d: the queue is full", id)
+		}
+	}
+}
+
+// drain stops accepting ideas and waits for the queued ones to be re-analyzed.
+// When the timeout passes, the running analysis is cancelled and the rest is dropped.
+func (q *reenrichQueue) drain(timeout time.Duration) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.ids)
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+	case <-time.After(timeout):
+		log.Printf("Re-analysis queue drain timed out after %s, dropping %d ideas", timeout, len(q.ids))
+		q.cancel()
+		<-q.done
+	}
+	q.cancel()
+}